@@ -32,6 +32,7 @@ import (
 	"github.com/go-piv/piv-go/piv"
 	"github.com/manifoldco/promptui"
 
+	"github.com/sigstore/cosign/v2/pkg/cosign"
 	"github.com/sigstore/cosign/v2/pkg/cosign/pivkey"
 )
 
@@ -258,7 +259,7 @@ func GenerateKeyCmd(ctx context.Context, managementKey string, randomKey bool, s
 		return err
 	}
 	pemBytes := pem.EncodeToMemory(&pem.Block{
-		Type:  "PUBLIC KEY",
+		Type:  cosign.PublicKeyPemType,
 		Bytes: b,
 	})
 
@@ -110,10 +110,8 @@ func (c *VerifyCommand) Exec(ctx context.Context, images []string) (err error) {
 		return flag.ErrHelp
 	}
 
-	// always default to sha256 if the algorithm hasn't been explicitly set
-	if c.HashAlgorithm == 0 {
-		c.HashAlgorithm = crypto.SHA256
-	}
+	// an unset HashAlgorithm selects the hash matching the key, which is what
+	// cosign signs with, see cosign.DefaultHashForKey
 
 	var identities []cosign.Identity
 	if c.KeyRef == "" {
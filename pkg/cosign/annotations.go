@@ -0,0 +1,164 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+)
+
+// OCI annotation keys used by cosign on signature layers.
+const (
+	SignatureAnnotationKey   = static.SignatureAnnotationKey
+	CertificateAnnotationKey = static.CertificateAnnotationKey
+	ChainAnnotationKey       = static.ChainAnnotationKey
+)
+
+// EncodeSignatureAnnotation returns the value of the SignatureAnnotationKey
+// annotation for a raw signature: standard, padded base64.
+func EncodeSignatureAnnotation(sig []byte) string {
+	return base64.StdEncoding.EncodeToString(sig)
+}
+
+// DecodeSignatureAnnotation returns the raw signature held by a
+// SignatureAnnotationKey annotation value, as encoded by
+// EncodeSignatureAnnotation.
+func DecodeSignatureAnnotation(s string) ([]byte, error) {
+	sig, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decoding signature annotation: %w", err)
+	}
+	return sig, nil
+}
+
+// SignatureAnnotations builds the annotations of a signature layer from the
+// raw signature and, if cert is not nil, the signing certificate and its chain.
+// The certificates are PEM-encoded the same way as when signing an image.
+func SignatureAnnotations(sig []byte, cert *x509.Certificate, chain []*x509.Certificate) (map[string]string, error) {
+	if len(sig) == 0 {
+		return nil, errors.New("empty signature")
+	}
+	annotations := map[string]string{
+		SignatureAnnotationKey: EncodeSignatureAnnotation(sig),
+	}
+	if cert == nil {
+		if len(chain) != 0 {
+			return nil, errors.New("certificate chain provided without a certificate")
+		}
+		return annotations, nil
+	}
+
+	certPem, err := cryptoutils.MarshalCertificateToPEM(cert)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling certificate: %w", err)
+	}
+	annotations[CertificateAnnotationKey] = string(certPem)
+
+	chainPem, err := cryptoutils.MarshalCertificatesToPEM(chain)
+	if err != nil {
+		return nil, fmt.Errorf("marshaling certificate chain: %w", err)
+	}
+	annotations[ChainAnnotationKey] = string(chainPem)
+	return annotations, nil
+}
+
+// MergeSignatureAnnotations merges the signature annotations of another
+// signing run into existing, and returns the result. Neither map is modified.
+//
+// The SignatureAnnotationKey, CertificateAnnotationKey and ChainAnnotationKey
+// values are turned into JSON arrays of strings, in signing order, so that
+// the i-th certificate and chain belong to the i-th signature. Use
+// SignatureAnnotationValues to read them back. Runs with and without a
+// certificate can't be merged, nor can other annotations with different
+// values.
+func MergeSignatureAnnotations(existing, added map[string]string) (map[string]string, error) {
+	merged := make(map[string]string, len(existing)+len(added))
+	for k, v := range existing {
+		merged[k] = v
+	}
+	if len(existing) == 0 {
+		for k, v := range added {
+			merged[k] = v
+		}
+		return merged, nil
+	}
+	for _, k := range []string{SignatureAnnotationKey, CertificateAnnotationKey, ChainAnnotationKey} {
+		_, inExisting := existing[k]
+		_, inAdded := added[k]
+		if inExisting != inAdded {
+			return nil, fmt.Errorf("cannot merge annotations with and without %s", k)
+		}
+	}
+
+	counts := map[string]int{}
+	for k, v := range added {
+		switch k {
+		case SignatureAnnotationKey, CertificateAnnotationKey, ChainAnnotationKey:
+		default:
+			if old, ok := existing[k]; ok && old != v {
+				return nil, fmt.Errorf("conflicting values for annotation %s", k)
+			}
+			merged[k] = v
+			continue
+		}
+		oldValues, err := SignatureAnnotationValues(existing[k])
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", k, err)
+		}
+		addedValues, err := SignatureAnnotationValues(v)
+		if err != nil {
+			return nil, fmt.Errorf("annotation %s: %w", k, err)
+		}
+		values := append(oldValues, addedValues...)
+		list, err := json.Marshal(values)
+		if err != nil {
+			return nil, err
+		}
+		merged[k] = string(list)
+		counts[k] = len(values)
+	}
+	for k, n := range counts {
+		if n != counts[SignatureAnnotationKey] {
+			return nil, fmt.Errorf("annotation %s doesn't have one value per signature", k)
+		}
+	}
+	return merged, nil
+}
+
+// SignatureAnnotationValues returns the values held by a signature annotation:
+// either a single value, or a JSON array of them as written by
+// MergeSignatureAnnotations. Neither base64 nor PEM start with a '[', so a
+// single value is never mistaken for a list.
+func SignatureAnnotationValues(v string) ([]string, error) {
+	if !strings.HasPrefix(v, "[") {
+		return []string{v}, nil
+	}
+	var values []string
+	if err := json.Unmarshal([]byte(v), &values); err != nil {
+		return nil, fmt.Errorf("parsing annotation list: %w", err)
+	}
+	if len(values) == 0 {
+		return nil, errors.New("empty annotation list")
+	}
+	return values, nil
+}
@@ -0,0 +1,151 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"testing"
+
+	"github.com/sigstore/cosign/v2/pkg/oci/static"
+	"github.com/sigstore/cosign/v2/test"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignatureAnnotations(t *testing.T) {
+	rootCert, rootKey, _ := test.GenerateRootCa()
+	subCert, subKey, _ := test.GenerateSubordinateCa(rootCert, rootKey)
+	leafCert, _, _ := test.GenerateLeafCert("subject@mail.com", "oidc-issuer", subCert, subKey)
+	chain := []*x509.Certificate{subCert, rootCert}
+
+	ann, err := SignatureAnnotations([]byte("sig"), nil, nil)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{SignatureAnnotationKey: "c2ln"}, ann)
+
+	ann, err = SignatureAnnotations([]byte("sig"), leafCert, chain)
+	require.NoError(t, err)
+	require.Len(t, ann, 3)
+	require.Equal(t, "c2ln", ann[SignatureAnnotationKey])
+	certs, err := cryptoutils.UnmarshalCertificatesFromPEM([]byte(ann[CertificateAnnotationKey]))
+	require.NoError(t, err)
+	require.Equal(t, []*x509.Certificate{leafCert}, certs)
+	certs, err = cryptoutils.UnmarshalCertificatesFromPEM([]byte(ann[ChainAnnotationKey]))
+	require.NoError(t, err)
+	require.Equal(t, chain, certs)
+
+	// The annotations match the ones set on a static signature
+	certPem, err := cryptoutils.MarshalCertificateToPEM(leafCert)
+	require.NoError(t, err)
+	chainPem, err := cryptoutils.MarshalCertificatesToPEM(chain)
+	require.NoError(t, err)
+	sig, err := static.NewSignature([]byte("payload"), "c2ln", static.WithCertChain(certPem, chainPem))
+	require.NoError(t, err)
+	sigAnn, err := sig.Annotations()
+	require.NoError(t, err)
+	require.Equal(t, sigAnn, ann)
+
+	_, err = SignatureAnnotations(nil, nil, nil)
+	require.EqualError(t, err, "empty signature")
+	_, err = SignatureAnnotations([]byte("sig"), nil, chain)
+	require.EqualError(t, err, "certificate chain provided without a certificate")
+}
+
+func TestSignatureAnnotationEncoding(t *testing.T) {
+	binary := make([]byte, 71)
+	_, err := rand.Read(binary)
+	require.NoError(t, err)
+	for _, sig := range [][]byte{{}, []byte("sig"), []byte("si"), {0x00, 0xff, 0xfb, 0xef}, binary} {
+		s := EncodeSignatureAnnotation(sig)
+		got, err := DecodeSignatureAnnotation(s)
+		require.NoError(t, err)
+		require.Equal(t, sig, got)
+	}
+
+	// Padded standard base64, as read back by the signature layers
+	require.Equal(t, "c2k=", EncodeSignatureAnnotation([]byte("si")))
+	require.Equal(t, "+//7", EncodeSignatureAnnotation([]byte{0xfb, 0xff, 0xfb}))
+	sig, err := static.NewSignature([]byte("payload"), EncodeSignatureAnnotation(binary))
+	require.NoError(t, err)
+	raw, err := sig.Signature()
+	require.NoError(t, err)
+	require.Equal(t, binary, raw)
+
+	for _, s := range []string{"c2k", "-__7", "c2ln!"} {
+		_, err := DecodeSignatureAnnotation(s)
+		require.ErrorContains(t, err, "decoding signature annotation")
+	}
+}
+
+func TestMergeSignatureAnnotations(t *testing.T) {
+	rootCert, rootKey, _ := test.GenerateRootCa()
+	leafCert, _, _ := test.GenerateLeafCert("subject", "oidc-issuer", rootCert, rootKey)
+	chain := []*x509.Certificate{rootCert}
+
+	first, err := SignatureAnnotations([]byte("sig1"), leafCert, chain)
+	require.NoError(t, err)
+	first["dev.sigstore.cosign/bundle"] = "bundle"
+	second, err := SignatureAnnotations([]byte("sig2"), leafCert, chain)
+	require.NoError(t, err)
+	second["dev.sigstore.cosign/bundle"] = "bundle"
+
+	// The first add keeps single values
+	merged, err := MergeSignatureAnnotations(nil, first)
+	require.NoError(t, err)
+	require.Equal(t, first, merged)
+
+	merged, err = MergeSignatureAnnotations(merged, second)
+	require.NoError(t, err)
+	require.Equal(t, `["c2lnMQ==","c2lnMg=="]`, merged[SignatureAnnotationKey])
+	require.Equal(t, "bundle", merged["dev.sigstore.cosign/bundle"])
+	require.Equal(t, "c2lnMQ==", first[SignatureAnnotationKey])
+	certs, err := SignatureAnnotationValues(merged[CertificateAnnotationKey])
+	require.NoError(t, err)
+	require.Equal(t, []string{first[CertificateAnnotationKey], second[CertificateAnnotationKey]}, certs)
+
+	third, err := SignatureAnnotations([]byte("sig3"), leafCert, chain)
+	require.NoError(t, err)
+	merged, err = MergeSignatureAnnotations(merged, third)
+	require.NoError(t, err)
+	sigs, err := SignatureAnnotationValues(merged[SignatureAnnotationKey])
+	require.NoError(t, err)
+	require.Equal(t, []string{"c2lnMQ==", "c2lnMg==", "c2lnMw=="}, sigs)
+	chains, err := SignatureAnnotationValues(merged[ChainAnnotationKey])
+	require.NoError(t, err)
+	require.Len(t, chains, 3)
+
+	keyed, err := SignatureAnnotations([]byte("sig4"), nil, nil)
+	require.NoError(t, err)
+	_, err = MergeSignatureAnnotations(first, keyed)
+	require.EqualError(t, err, "cannot merge annotations with and without "+CertificateAnnotationKey)
+
+	second["dev.sigstore.cosign/bundle"] = "other"
+	_, err = MergeSignatureAnnotations(first, second)
+	require.EqualError(t, err, "conflicting values for annotation dev.sigstore.cosign/bundle")
+
+	_, err = MergeSignatureAnnotations(map[string]string{SignatureAnnotationKey: "[oops"}, keyed)
+	require.ErrorContains(t, err, "parsing annotation list")
+	_, err = MergeSignatureAnnotations(map[string]string{SignatureAnnotationKey: "[]"}, keyed)
+	require.EqualError(t, err, "annotation "+SignatureAnnotationKey+": empty annotation list")
+
+	lopsided := map[string]string{
+		SignatureAnnotationKey:   `["c2lnMQ==","c2lnMg=="]`,
+		CertificateAnnotationKey: first[CertificateAnnotationKey],
+		ChainAnnotationKey:       first[ChainAnnotationKey],
+	}
+	_, err = MergeSignatureAnnotations(lopsided, third)
+	require.ErrorContains(t, err, "doesn't have one value per signature")
+}
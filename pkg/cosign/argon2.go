@@ -0,0 +1,156 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// KDF is the key derivation function used to encrypt private keys.
+type KDF string
+
+const (
+	// KDFScrypt encrypts private keys with the scrypt based format of the
+	// go-securesystemslib encrypted package. It is the default.
+	KDFScrypt KDF = "scrypt"
+	// KDFArgon2id encrypts private keys with a key derived by Argon2id, using
+	// the RFC 9106 recommended parameters for memory constrained
+	// environments (t=3, m=64 MiB, p=4).
+	KDFArgon2id KDF = "argon2id"
+)
+
+const (
+	argon2idTime    = 3
+	argon2idMemory  = 64 * 1024
+	argon2idThreads = 4
+
+	// maxArgon2idTime and maxArgon2idMemory bound the work an attacker
+	// controlled key file can cause when it is decrypted. The memory is in
+	// KiB, so at most 256 MiB are allocated per load.
+	maxArgon2idTime   = 16
+	maxArgon2idMemory = 256 * 1024
+
+	secretboxKeySize   = 32
+	secretboxNonceSize = 24
+	argon2idSaltSize   = 32
+	// minArgon2idSaltSize is the salt size recommended by RFC 9106.
+	minArgon2idSaltSize = 16
+)
+
+// argon2idEnvelope mirrors the JSON envelope of the encrypted package, so that
+// both formats can be told apart by their "kdf" name.
+type argon2idEnvelope struct {
+	KDF        argon2idKDF    `json:"kdf"`
+	Cipher     envelopeCipher `json:"cipher"`
+	Ciphertext []byte         `json:"ciphertext"`
+}
+
+type argon2idKDF struct {
+	Name   string         `json:"name"`
+	Params argon2idParams `json:"params"`
+	Salt   []byte         `json:"salt"`
+}
+
+type argon2idParams struct {
+	Time    uint32 `json:"t"`
+	Memory  uint32 `json:"m"`
+	Threads uint8  `json:"p"`
+}
+
+type envelopeCipher struct {
+	Name  string `json:"name"`
+	Nonce []byte `json:"nonce"`
+}
+
+const nameSecretBox = "nacl/secretbox"
+
+func (p argon2idParams) check() error {
+	if p.Time == 0 || p.Time > maxArgon2idTime || p.Threads == 0 ||
+		p.Memory < 8*uint32(p.Threads) || p.Memory > maxArgon2idMemory {
+		return errors.New("unsupported argon2id parameters")
+	}
+	return nil
+}
+
+func encryptArgon2id(plaintext, pass []byte) ([]byte, error) {
+	env := argon2idEnvelope{
+		KDF: argon2idKDF{
+			Name:   string(KDFArgon2id),
+			Params: argon2idParams{Time: argon2idTime, Memory: argon2idMemory, Threads: argon2idThreads},
+			Salt:   make([]byte, argon2idSaltSize),
+		},
+		Cipher: envelopeCipher{
+			Name:  nameSecretBox,
+			Nonce: make([]byte, secretboxNonceSize),
+		},
+	}
+	if _, err := io.ReadFull(rand.Reader, env.KDF.Salt); err != nil {
+		return nil, err
+	}
+	if _, err := io.ReadFull(rand.Reader, env.Cipher.Nonce); err != nil {
+		return nil, err
+	}
+	var key [secretboxKeySize]byte
+	var nonce [secretboxNonceSize]byte
+	copy(key[:], env.KDF.key(pass))
+	defer clear(key[:])
+	copy(nonce[:], env.Cipher.Nonce)
+	env.Ciphertext = secretbox.Seal(nil, plaintext, &nonce, &key)
+	return json.Marshal(env)
+}
+
+func decryptArgon2id(data, pass []byte) ([]byte, error) {
+	var env argon2idEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if env.KDF.Name != string(KDFArgon2id) {
+		return nil, fmt.Errorf("unknown kdf name %q", env.KDF.Name)
+	}
+	if len(env.KDF.Salt) < minArgon2idSaltSize {
+		return nil, errors.New("incorrect salt size")
+	}
+	if env.Cipher.Name != nameSecretBox {
+		return nil, fmt.Errorf("unknown cipher name %q", env.Cipher.Name)
+	}
+	if len(env.Cipher.Nonce) != secretboxNonceSize {
+		return nil, errors.New("incorrect nonce size")
+	}
+	if err := env.KDF.Params.check(); err != nil {
+		return nil, err
+	}
+	var key [secretboxKeySize]byte
+	var nonce [secretboxNonceSize]byte
+	copy(key[:], env.KDF.key(pass))
+	defer clear(key[:])
+	copy(nonce[:], env.Cipher.Nonce)
+	plaintext, ok := secretbox.Open(nil, env.Ciphertext, &nonce, &key)
+	if !ok {
+		return nil, errors.New("decryption failed")
+	}
+	return plaintext, nil
+}
+
+func (k argon2idKDF) key(pass []byte) []byte {
+	return argon2.IDKey(pass, k.Salt, k.Params.Time, k.Params.Memory, k.Params.Threads, secretboxKeySize)
+}
@@ -0,0 +1,103 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateKeyPairArgon2id(t *testing.T) {
+	keys, err := GenerateKeyPairWithOptions(pass("hello"), KeyPairOpts{KDF: KDFArgon2id})
+	require.NoError(t, err)
+	p, _ := pem.Decode(keys.PrivateBytes)
+	require.Equal(t, SigstorePrivateKeyPemType, p.Type)
+	require.Equal(t, KDFArgon2id, envelopeKDF(p.Bytes))
+	require.NoError(t, keys.Validate([]byte("hello")))
+
+	sv, err := LoadPrivateKey(keys.PrivateBytes, []byte("hello"))
+	require.NoError(t, err)
+	sig, err := sv.SignMessage(bytes.NewReader([]byte("payload")))
+	require.NoError(t, err)
+	require.NoError(t, VerifyBytes(mustLoadPublicKey(t, keys.PublicBytes), []byte("payload"), sig))
+
+	_, err = LoadPrivateKey(keys.PrivateBytes, []byte("wrong"))
+	require.ErrorIs(t, err, ErrDecryptFailed)
+	_, err = LoadPrivateKey(keys.PrivateBytes, nil)
+	require.ErrorIs(t, err, ErrPasswordRequired)
+
+	// Changing the password keeps the KDF
+	changed, err := ChangePrivateKeyPassword(keys.PrivateBytes, []byte("hello"), []byte("world"))
+	require.NoError(t, err)
+	p, _ = pem.Decode(changed)
+	require.Equal(t, KDFArgon2id, envelopeKDF(p.Bytes))
+	_, err = LoadPrivateKey(changed, []byte("world"))
+	require.NoError(t, err)
+
+	// The default is still scrypt
+	keys, err = GenerateKeyPairWithOptions(pass("hello"), KeyPairOpts{})
+	require.NoError(t, err)
+	p, _ = pem.Decode(keys.PrivateBytes)
+	require.Equal(t, KDFScrypt, envelopeKDF(p.Bytes))
+
+	_, err = GenerateKeyPairWithOptions(pass("hello"), KeyPairOpts{KDF: "bcrypt"})
+	require.ErrorIs(t, err, ErrEncryptPrivateKey)
+}
+
+func TestArgon2idTamper(t *testing.T) {
+	enc, err := encryptArgon2id([]byte("secret"), []byte("hello"))
+	require.NoError(t, err)
+	plaintext, err := decryptArgon2id(enc, []byte("hello"))
+	require.NoError(t, err)
+	require.Equal(t, []byte("secret"), plaintext)
+
+	tamper := func(f func(env *argon2idEnvelope)) []byte {
+		var env argon2idEnvelope
+		require.NoError(t, json.Unmarshal(enc, &env))
+		f(&env)
+		b, err := json.Marshal(env)
+		require.NoError(t, err)
+		return b
+	}
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr string
+	}{
+		{"ciphertext", tamper(func(env *argon2idEnvelope) { env.Ciphertext[0] ^= 1 }), "decryption failed"},
+		{"salt", tamper(func(env *argon2idEnvelope) { env.KDF.Salt[0] ^= 1 }), "decryption failed"},
+		{"nonce", tamper(func(env *argon2idEnvelope) { env.Cipher.Nonce[0] ^= 1 }), "decryption failed"},
+		{"time", tamper(func(env *argon2idEnvelope) { env.KDF.Params.Time = 2 }), "decryption failed"},
+		{"nonce size", tamper(func(env *argon2idEnvelope) { env.Cipher.Nonce = env.Cipher.Nonce[1:] }), "incorrect nonce size"},
+		{"cipher", tamper(func(env *argon2idEnvelope) { env.Cipher.Name = "aes" }), `unknown cipher name "aes"`},
+		{"kdf", tamper(func(env *argon2idEnvelope) { env.KDF.Name = "scrypt" }), `unknown kdf name "scrypt"`},
+		{"no salt", tamper(func(env *argon2idEnvelope) { env.KDF.Salt = nil }), "incorrect salt size"},
+		{"short salt", tamper(func(env *argon2idEnvelope) { env.KDF.Salt = env.KDF.Salt[:minArgon2idSaltSize-1] }), "incorrect salt size"},
+		{"memory dos", tamper(func(env *argon2idEnvelope) { env.KDF.Params.Memory = 512 * 1024 }), "unsupported argon2id parameters"},
+		{"time dos", tamper(func(env *argon2idEnvelope) { env.KDF.Params.Time = 1 << 20 }), "unsupported argon2id parameters"},
+		{"no threads", tamper(func(env *argon2idEnvelope) { env.KDF.Params.Threads = 0 }), "unsupported argon2id parameters"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decryptArgon2id(tt.data, []byte("hello"))
+			require.EqualError(t, err, tt.wantErr)
+		})
+	}
+}
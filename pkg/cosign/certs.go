@@ -0,0 +1,223 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// CertChainToPem encodes each certificate of the chain, in order, as a
+// CERTIFICATE PEM block, with cryptoutils.MarshalCertificatesToPEM. Blocks are
+// separated by a single newline.
+func CertChainToPem(chain []*x509.Certificate) ([]byte, error) {
+	return cryptoutils.MarshalCertificatesToPEM(chain)
+}
+
+// CertToPemStrict encodes cert as a CERTIFICATE PEM block in the strict
+// RFC 7468 encoding of KeyToPemStrict.
+func CertToPemStrict(cert *x509.Certificate) ([]byte, error) {
+	if cert == nil {
+		return nil, errors.New("certificate is nil")
+	}
+	return encodeStrictPem(string(cryptoutils.CertificatePEMType), cert.Raw), nil
+}
+
+// LoadCertChainFromPem parses all concatenated PEM blocks of pemBytes as
+// certificates, with cryptoutils.UnmarshalCertificatesFromPEMLimited. It
+// returns an error if any block is not a certificate, or if there are more
+// than maxPemBlocks blocks.
+func LoadCertChainFromPem(pemBytes []byte) ([]*x509.Certificate, error) {
+	return cryptoutils.UnmarshalCertificatesFromPEMLimited(pemBytes, maxPemBlocks)
+}
+
+// VerifyCertChain parses the PEM-encoded leaf certificate, its intermediate
+// certificates and the trusted root certificates with LoadCertChainFromPem,
+// and verifies that the leaf chains to one of the roots. chainPem may be
+// empty, and may also hold the root, as with the chain annotation of cosign
+// signatures. The Roots and Intermediates of opts are replaced by the parsed
+// certificates; if opts.KeyUsages is empty, code signing is required. It
+// returns the verified chains, each starting with the leaf and ending with a
+// root.
+func VerifyCertChain(leafPem, chainPem, rootPem []byte, opts x509.VerifyOptions) ([][]*x509.Certificate, error) {
+	leaves, err := LoadCertChainFromPem(leafPem)
+	if err != nil {
+		return nil, fmt.Errorf("loading leaf certificate: %w", err)
+	}
+	if len(leaves) != 1 {
+		return nil, fmt.Errorf("expected one leaf certificate, got %d", len(leaves))
+	}
+	intermediates, err := LoadCertChainFromPem(chainPem)
+	if err != nil {
+		return nil, fmt.Errorf("loading certificate chain: %w", err)
+	}
+	roots, err := LoadCertChainFromPem(rootPem)
+	if err != nil {
+		return nil, fmt.Errorf("loading root certificates: %w", err)
+	}
+	if len(roots) == 0 {
+		return nil, errors.New("no root certificates")
+	}
+
+	opts.Roots = x509.NewCertPool()
+	for _, root := range roots {
+		opts.Roots.AddCert(root)
+	}
+	opts.Intermediates = x509.NewCertPool()
+	for _, cert := range intermediates {
+		opts.Intermediates.AddCert(cert)
+	}
+	if len(opts.KeyUsages) == 0 {
+		opts.KeyUsages = []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}
+	}
+	chains, err := leaves[0].Verify(opts)
+	if err != nil {
+		return nil, fmt.Errorf("verifying certificate chain: %w", err)
+	}
+	return chains, nil
+}
+
+// ParsePemBundle splits a PEM bundle mixing public keys and certificates, such
+// as a public key followed by its signing certificate and chain. PUBLIC KEY and
+// CERTIFICATE blocks are returned in the order they appear; any other block
+// type is an error.
+func ParsePemBundle(pemBytes []byte) (publicKeys []crypto.PublicKey, certs []*x509.Certificate, err error) {
+	publicKeys = []crypto.PublicKey{}
+	certs = []*x509.Certificate{}
+	rest := bytes.TrimSpace(pemBytes)
+	for len(rest) > 0 {
+		p, next, err := decodePemSafely(rest)
+		if err != nil {
+			return nil, nil, err
+		}
+		if p == nil {
+			return nil, nil, ErrInvalidPemBlock
+		}
+		rest = next
+		switch p.Type {
+		case PublicKeyPemType:
+			pub, err := parsePKIXPublicKey(p.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing public key %d: %w", len(publicKeys), err)
+			}
+			publicKeys = append(publicKeys, pub)
+		case string(cryptoutils.CertificatePEMType):
+			cert, err := x509.ParseCertificate(p.Bytes)
+			if err != nil {
+				return nil, nil, fmt.Errorf("parsing certificate %d: %w", len(certs), err)
+			}
+			certs = append(certs, cert)
+		default:
+			return nil, nil, fmt.Errorf("%w: %s", ErrUnsupportedPemType, p.Type)
+		}
+		rest = bytes.TrimSpace(rest)
+	}
+	return publicKeys, certs, nil
+}
+
+// GenerateKeyPairWithSelfSignedCert generates a key pair like GenerateKeyPair,
+// along with a self-signed code signing certificate for its public key, valid
+// from now for the given duration. The certificate is returned PEM-encoded, so
+// that it can be distributed instead of the bare public key.
+func GenerateKeyPairWithSelfSignedCert(pf PassFunc, subject pkix.Name, validity time.Duration) (*KeysBytes, []byte, error) {
+	if validity <= 0 {
+		return nil, nil, errors.New("certificate validity must be positive")
+	}
+	keys, priv, err := generateKeyPair(pf, KeyPairOpts{})
+	if err != nil {
+		return nil, nil, err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("generating serial number: %w", err)
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               subject,
+		NotBefore:             now,
+		NotAfter:              now.Add(validity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning},
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, priv.Public(), priv)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, nil, fmt.Errorf("parsing certificate: %w", err)
+	}
+	certPem, err := cryptoutils.MarshalCertificateToPEM(cert)
+	if err != nil {
+		return nil, nil, err
+	}
+	return keys, certPem, nil
+}
+
+// CertVerifyOpts are the options of VerifyWithCertPemOptions.
+type CertVerifyOpts struct {
+	// SkipExpiryCheck accepts certificates outside of their validity window.
+	SkipExpiryCheck bool
+	// CurrentTime is the time the validity window is checked against. It
+	// defaults to now.
+	CurrentTime time.Time
+}
+
+// VerifyWithCertPem verifies sig over payload with the public key of the
+// PEM-encoded certificate, hashed with DefaultHashForKey. The certificate must
+// be within its validity window; its chain is not verified.
+func VerifyWithCertPem(certPem []byte, payload, sig []byte) error {
+	return VerifyWithCertPemOptions(certPem, payload, sig, CertVerifyOpts{})
+}
+
+// VerifyWithCertPemOptions is VerifyWithCertPem with options.
+func VerifyWithCertPemOptions(certPem []byte, payload, sig []byte, opts CertVerifyOpts) error {
+	certs, err := LoadCertChainFromPem(certPem)
+	if err != nil {
+		return err
+	}
+	if len(certs) != 1 {
+		return fmt.Errorf("expected one certificate, got %d", len(certs))
+	}
+	cert := certs[0]
+	if !opts.SkipExpiryCheck {
+		now := opts.CurrentTime
+		if now.IsZero() {
+			now = time.Now()
+		}
+		if now.Before(cert.NotBefore) || now.After(cert.NotAfter) {
+			return fmt.Errorf("certificate is not valid at %s: valid from %s to %s",
+				now.UTC().Format(time.RFC3339), cert.NotBefore.UTC().Format(time.RFC3339), cert.NotAfter.UTC().Format(time.RFC3339))
+		}
+	}
+	verifier, err := signature.LoadVerifier(cert.PublicKey, DefaultHashForKey(cert.PublicKey))
+	if err != nil {
+		return fmt.Errorf("loading certificate verifier: %w", err)
+	}
+	return verifier.VerifySignature(bytes.NewReader(sig), bytes.NewReader(payload))
+}
@@ -0,0 +1,211 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sigstore/cosign/v2/test"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCertChainToPem(t *testing.T) {
+	rootCert, rootKey, _ := test.GenerateRootCa()
+	subCert, subKey, _ := test.GenerateSubordinateCa(rootCert, rootKey)
+	leafCert, _, _ := test.GenerateLeafCert("subject@mail.com", "oidc-issuer", subCert, subKey)
+	chain := []*x509.Certificate{leafCert, subCert, rootCert}
+
+	pemBytes, err := CertChainToPem(chain)
+	require.NoError(t, err)
+	require.Equal(t, 3, bytes.Count(pemBytes, []byte("-----BEGIN CERTIFICATE-----")))
+	require.Equal(t, 2, bytes.Count(pemBytes, []byte("-----\n-----BEGIN")))
+	require.NotContains(t, string(pemBytes), "\n\n")
+	require.True(t, bytes.HasSuffix(pemBytes, []byte("-----END CERTIFICATE-----\n")))
+
+	certs, err := LoadCertChainFromPem(pemBytes)
+	require.NoError(t, err)
+	require.Equal(t, chain, certs)
+
+	empty, err := CertChainToPem(nil)
+	require.NoError(t, err)
+	require.Empty(t, empty)
+	certs, err = LoadCertChainFromPem(empty)
+	require.NoError(t, err)
+	require.Empty(t, certs)
+
+	_, err = CertChainToPem([]*x509.Certificate{leafCert, nil})
+	require.EqualError(t, err, "nil certificate provided")
+
+	keys, err := GenerateKeyPair(pass("hello"))
+	require.NoError(t, err)
+	_, err = LoadCertChainFromPem(append(pemBytes, keys.PublicBytes...))
+	require.Error(t, err)
+	_, err = LoadCertChainFromPem(append(pemBytes, []byte("garbage")...))
+	require.EqualError(t, err, "error during PEM decoding")
+}
+
+func TestCertToPemStrict(t *testing.T) {
+	// Golden file generated with `openssl x509`
+	want, err := os.ReadFile(filepath.Join("testdata", "strict-certificate.pem"))
+	require.NoError(t, err)
+	certs, err := LoadCertChainFromPem([]byte(testLeafCert))
+	require.NoError(t, err)
+	got, err := CertToPemStrict(certs[0])
+	require.NoError(t, err)
+	require.Equal(t, string(want), string(got))
+
+	_, err = CertToPemStrict(nil)
+	require.EqualError(t, err, "certificate is nil")
+}
+
+func TestParsePemBundle(t *testing.T) {
+	rootCert, rootKey, _ := test.GenerateRootCa()
+	subCert, subKey, _ := test.GenerateSubordinateCa(rootCert, rootKey)
+	leafCert, leafKey, _ := test.GenerateLeafCert("subject@mail.com", "oidc-issuer", subCert, subKey)
+
+	pubPem, err := KeyToPem(leafKey.Public())
+	require.NoError(t, err)
+	chainPem, err := CertChainToPem([]*x509.Certificate{leafCert, subCert, rootCert})
+	require.NoError(t, err)
+	bundle := append(append(pubPem, '\n'), chainPem...)
+
+	keys, certs, err := ParsePemBundle(bundle)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.True(t, EqualPublicKeys(leafKey.Public(), keys[0]))
+	require.Len(t, certs, 3)
+	require.Equal(t, leafCert.Raw, certs[0].Raw)
+	require.Equal(t, subCert.Raw, certs[1].Raw)
+	require.Equal(t, rootCert.Raw, certs[2].Raw)
+
+	keys, certs, err = ParsePemBundle(nil)
+	require.NoError(t, err)
+	require.Empty(t, keys)
+	require.Empty(t, certs)
+
+	_, _, err = ParsePemBundle(append(chainPem, []byte(pemcosignkey)...))
+	require.ErrorIs(t, err, ErrUnsupportedPemType)
+	_, _, err = ParsePemBundle(append(pubPem, []byte("garbage")...))
+	require.ErrorIs(t, err, ErrInvalidPemBlock)
+}
+
+func TestGenerateKeyPairWithSelfSignedCert(t *testing.T) {
+	subject := pkix.Name{CommonName: "cosign test", Organization: []string{"sigstore"}}
+	before := time.Now().Truncate(time.Second)
+	keys, certPem, err := GenerateKeyPairWithSelfSignedCert(pass("hello"), subject, 24*time.Hour)
+	require.NoError(t, err)
+	require.NoError(t, keys.Validate([]byte("hello")))
+
+	certs, err := LoadCertChainFromPem(certPem)
+	require.NoError(t, err)
+	require.Len(t, certs, 1)
+	cert := certs[0]
+	require.True(t, EqualPublicKeys(mustLoadPublicKey(t, keys.PublicBytes), cert.PublicKey))
+	require.Equal(t, "cosign test", cert.Subject.CommonName)
+	require.Equal(t, cert.Subject.String(), cert.Issuer.String())
+	require.NoError(t, cert.CheckSignature(cert.SignatureAlgorithm, cert.RawTBSCertificate, cert.Signature))
+	require.False(t, cert.NotBefore.Before(before))
+	require.Equal(t, 24*time.Hour, cert.NotAfter.Sub(cert.NotBefore))
+	require.Equal(t, []x509.ExtKeyUsage{x509.ExtKeyUsageCodeSigning}, cert.ExtKeyUsage)
+
+	_, _, err = GenerateKeyPairWithSelfSignedCert(pass("hello"), subject, 0)
+	require.EqualError(t, err, "certificate validity must be positive")
+}
+
+func TestVerifyWithCertPem(t *testing.T) {
+	keys, certPem, err := GenerateKeyPairWithSelfSignedCert(pass("hello"), pkix.Name{CommonName: "cosign test"}, time.Hour)
+	require.NoError(t, err)
+	payload := []byte("payload")
+	sig, err := SignBytes(keys.PrivateBytes, []byte("hello"), payload)
+	require.NoError(t, err)
+
+	require.NoError(t, VerifyWithCertPem(certPem, payload, sig))
+	require.Error(t, VerifyWithCertPem(certPem, []byte("other payload"), sig))
+
+	// Expired, or not yet valid, certificates are rejected unless the check
+	// is skipped
+	expired := CertVerifyOpts{CurrentTime: time.Now().Add(2 * time.Hour)}
+	require.ErrorContains(t, VerifyWithCertPemOptions(certPem, payload, sig, expired), "certificate is not valid at")
+	notYetValid := CertVerifyOpts{CurrentTime: time.Now().Add(-time.Hour)}
+	require.ErrorContains(t, VerifyWithCertPemOptions(certPem, payload, sig, notYetValid), "certificate is not valid at")
+	expired.SkipExpiryCheck = true
+	require.NoError(t, VerifyWithCertPemOptions(certPem, payload, sig, expired))
+
+	_, otherCert, err := GenerateKeyPairWithSelfSignedCert(pass("hello"), pkix.Name{CommonName: "other"}, time.Hour)
+	require.NoError(t, err)
+	require.Error(t, VerifyWithCertPem(otherCert, payload, sig))
+	require.EqualError(t, VerifyWithCertPem(append(certPem, otherCert...), payload, sig), "expected one certificate, got 2")
+	require.Error(t, VerifyWithCertPem(keys.PublicBytes, payload, sig))
+}
+
+func TestVerifyCertChain(t *testing.T) {
+	rootCert, rootKey, _ := test.GenerateRootCa()
+	subCert, subKey, _ := test.GenerateSubordinateCa(rootCert, rootKey)
+	leafCert, _, _ := test.GenerateLeafCert("subject@mail.com", "oidc-issuer", subCert, subKey)
+	leafPem, err := CertChainToPem([]*x509.Certificate{leafCert})
+	require.NoError(t, err)
+	chainPem, err := CertChainToPem([]*x509.Certificate{subCert, rootCert})
+	require.NoError(t, err)
+	rootPem, err := CertChainToPem([]*x509.Certificate{rootCert})
+	require.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		chains, err := VerifyCertChain(leafPem, chainPem, rootPem, x509.VerifyOptions{})
+		require.NoError(t, err)
+		require.Equal(t, [][]*x509.Certificate{{leafCert, subCert, rootCert}}, chains)
+	})
+
+	t.Run("missing intermediate", func(t *testing.T) {
+		_, err := VerifyCertChain(leafPem, nil, rootPem, x509.VerifyOptions{})
+		require.ErrorContains(t, err, "verifying certificate chain")
+	})
+
+	t.Run("expired", func(t *testing.T) {
+		_, err := VerifyCertChain(leafPem, chainPem, rootPem, x509.VerifyOptions{CurrentTime: time.Now().Add(24 * time.Hour)})
+		var invalid x509.CertificateInvalidError
+		require.ErrorAs(t, err, &invalid)
+		require.Equal(t, x509.Expired, invalid.Reason)
+	})
+
+	t.Run("untrusted root", func(t *testing.T) {
+		otherRoot, _, _ := test.GenerateRootCa()
+		otherPem, err := CertChainToPem([]*x509.Certificate{otherRoot})
+		require.NoError(t, err)
+		_, err = VerifyCertChain(leafPem, chainPem, otherPem, x509.VerifyOptions{})
+		var unknown x509.UnknownAuthorityError
+		require.ErrorAs(t, err, &unknown)
+	})
+
+	t.Run("wrong key usage", func(t *testing.T) {
+		_, err := VerifyCertChain(leafPem, chainPem, rootPem, x509.VerifyOptions{KeyUsages: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}})
+		require.ErrorContains(t, err, "verifying certificate chain")
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, err := VerifyCertChain(append(leafPem, chainPem...), nil, rootPem, x509.VerifyOptions{})
+		require.EqualError(t, err, "expected one leaf certificate, got 3")
+		_, err = VerifyCertChain(leafPem, chainPem, nil, x509.VerifyOptions{})
+		require.EqualError(t, err, "no root certificates")
+		_, err = VerifyCertChain(leafPem, []byte("garbage"), rootPem, x509.VerifyOptions{})
+		require.EqualError(t, err, "loading certificate chain: error during PEM decoding")
+	})
+}
@@ -16,7 +16,6 @@
 package cosign
 
 import (
-	"errors"
 	"fmt"
 	"os"
 	"syscall"
@@ -47,7 +46,7 @@ func GetPassFromTerm(confirm bool) ([]byte, error) {
 	}
 
 	if string(pw1) != string(confirmpw) {
-		return nil, errors.New("passwords do not match")
+		return nil, ErrPassphraseMismatch
 	}
 	return pw1, nil
 }
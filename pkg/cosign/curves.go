@@ -0,0 +1,420 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CurveRegistry maps names to the elliptic curves available for ECDSA keys.
+// The key generation and loading functions of this package consult
+// DefaultCurveRegistry: a curve registered there under name can be generated
+// with the "ecdsa-<name>" algorithm, and keys on that curve can be encoded
+// and loaded like keys on the NIST curves. It is safe for concurrent use.
+//
+// WARNING: this is meant for tests and research deployments only. Keys on
+// custom curves are not supported by cosign verifiers, Fulcio or Rekor, and
+// the Go standard library handles such curves with a slow implementation
+// that is not constant-time.
+type CurveRegistry struct {
+	mu     sync.RWMutex
+	curves map[string]registeredCurve
+}
+
+type registeredCurve struct {
+	curve elliptic.Curve
+	oid   asn1.ObjectIdentifier
+	// custom is false for the curves already supported by crypto/x509.
+	custom bool
+}
+
+// DefaultCurveRegistry is the CurveRegistry consulted by this package. It
+// holds the NIST P-256, P-384 and P-521 curves by default.
+var DefaultCurveRegistry = NewCurveRegistry()
+
+// NewCurveRegistry returns a CurveRegistry holding the NIST P-256, P-384 and
+// P-521 curves, named "p256", "p384" and "p521".
+func NewCurveRegistry() *CurveRegistry {
+	return &CurveRegistry{
+		curves: map[string]registeredCurve{
+			"p256": {curve: elliptic.P256(), oid: asn1.ObjectIdentifier{1, 2, 840, 10045, 3, 1, 7}},
+			"p384": {curve: elliptic.P384(), oid: asn1.ObjectIdentifier{1, 3, 132, 0, 34}},
+			"p521": {curve: elliptic.P521(), oid: asn1.ObjectIdentifier{1, 3, 132, 0, 35}},
+		},
+	}
+}
+
+// Register adds curve under name. oid identifies the curve in encoded keys.
+// Neither the name, the curve nor the OID may already be registered. Names
+// are case-insensitive.
+func (r *CurveRegistry) Register(name string, curve elliptic.Curve, oid asn1.ObjectIdentifier) error {
+	name = strings.ToLower(name)
+	if name == "" || curve == nil || len(oid) == 0 {
+		return errors.New("curve name, curve and oid are required")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for n, c := range r.curves {
+		switch {
+		case n == name:
+			return fmt.Errorf("curve %s is already registered", name)
+		case c.curve == curve:
+			return fmt.Errorf("curve %s is already registered as %s", curve.Params().Name, n)
+		case c.oid.Equal(oid):
+			return fmt.Errorf("curve oid %s is already registered for %s", oid, n)
+		}
+	}
+	r.curves[name] = registeredCurve{curve: curve, oid: oid, custom: true}
+	return nil
+}
+
+// Lookup returns the curve registered under name.
+func (r *CurveRegistry) Lookup(name string) (elliptic.Curve, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c, ok := r.curves[strings.ToLower(name)]
+	return c.curve, ok
+}
+
+// Names returns the sorted names of the registered curves.
+func (r *CurveRegistry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	names := make([]string, 0, len(r.curves))
+	for n := range r.curves {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// curveForParams returns the registered curve with the domain parameters of
+// params. The standard curves are preferred over custom curves registered
+// with the same parameters.
+func (r *CurveRegistry) curveForParams(params *elliptic.CurveParams) (registeredCurve, bool) {
+	if params == nil || params.P == nil || params.N == nil || params.B == nil || params.Gx == nil || params.Gy == nil {
+		return registeredCurve{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	var match registeredCurve
+	var ok bool
+	for _, c := range r.curves {
+		cp := c.curve.Params()
+		if cp.P.Cmp(params.P) == 0 && cp.N.Cmp(params.N) == 0 && cp.B.Cmp(params.B) == 0 &&
+			cp.Gx.Cmp(params.Gx) == 0 && cp.Gy.Cmp(params.Gy) == 0 && (!ok || match.custom) {
+			match, ok = c, true
+		}
+	}
+	return match, ok
+}
+
+// isRegisteredCurve reports whether curve is one of the registered curves.
+func (r *CurveRegistry) isRegisteredCurve(curve elliptic.Curve) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.curves {
+		if c.curve == curve {
+			return true
+		}
+	}
+	return false
+}
+
+// customCurve returns the registered custom curve matching curve or oid.
+func (r *CurveRegistry) customCurve(curve elliptic.Curve, oid asn1.ObjectIdentifier) (registeredCurve, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, c := range r.curves {
+		if c.custom && (c.curve == curve || (oid != nil && c.oid.Equal(oid))) {
+			return c, true
+		}
+	}
+	return registeredCurve{}, false
+}
+
+var oidPublicKeyECDSA = asn1.ObjectIdentifier{1, 2, 840, 10045, 2, 1}
+
+// pkcs8PrivateKey is the PrivateKeyInfo structure of RFC 5208.
+type pkcs8PrivateKey struct {
+	Version    int
+	Algo       pkix.AlgorithmIdentifier
+	PrivateKey []byte
+}
+
+// sec1PrivateKey is the ECPrivateKey structure of RFC 5915.
+type sec1PrivateKey struct {
+	Version       int
+	PrivateKey    []byte
+	NamedCurveOID asn1.ObjectIdentifier `asn1:"optional,explicit,tag:0"`
+	PublicKey     asn1.BitString        `asn1:"optional,explicit,tag:1"`
+}
+
+// pkixPublicKey is the SubjectPublicKeyInfo structure of RFC 5280.
+type pkixPublicKey struct {
+	Algo      pkix.AlgorithmIdentifier
+	PublicKey asn1.BitString
+}
+
+// marshalPKCS8PrivateKey is x509.MarshalPKCS8PrivateKey, extended to ECDSA
+// keys on the custom curves of DefaultCurveRegistry.
+func marshalPKCS8PrivateKey(pk crypto.PrivateKey) ([]byte, error) {
+	ecdsaPk, ok := pk.(*ecdsa.PrivateKey)
+	if !ok {
+		return x509.MarshalPKCS8PrivateKey(pk)
+	}
+	c, ok := DefaultCurveRegistry.customCurve(ecdsaPk.Curve, nil)
+	if !ok {
+		return x509.MarshalPKCS8PrivateKey(pk)
+	}
+	size := (c.curve.Params().N.BitLen() + 7) / 8
+	point := marshalPoint(c.curve, ecdsaPk.X, ecdsaPk.Y)
+	sec1, err := asn1.Marshal(sec1PrivateKey{
+		Version:    1,
+		PrivateKey: ecdsaPk.D.FillBytes(make([]byte, size)),
+		PublicKey:  asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+	if err != nil {
+		return nil, err
+	}
+	algo, err := curveAlgorithmIdentifier(c.oid)
+	if err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(pkcs8PrivateKey{Algo: algo, PrivateKey: sec1})
+}
+
+// parsePKCS8PrivateKeyDER is x509.ParsePKCS8PrivateKey, extended to ECDSA
+// keys on the custom curves of DefaultCurveRegistry. Parser panics are
+// returned as errors.
+func parsePKCS8PrivateKeyDER(der []byte) (_ crypto.PrivateKey, err error) {
+	defer recoverParsePanic(&err)
+	pk, err := x509.ParsePKCS8PrivateKey(der)
+	if err == nil {
+		return pk, nil
+	}
+	var info pkcs8PrivateKey
+	if unmarshalDER(der, &info) != nil {
+		return nil, err
+	}
+	c, ok := customCurveForAlgorithm(info.Algo)
+	if !ok {
+		return nil, err
+	}
+	var sec1 sec1PrivateKey
+	if err := unmarshalDER(info.PrivateKey, &sec1); err != nil {
+		return nil, fmt.Errorf("parsing ec private key: %w", err)
+	}
+	params := c.curve.Params()
+	d := new(big.Int).SetBytes(sec1.PrivateKey)
+	if d.Sign() <= 0 || d.Cmp(params.N) >= 0 {
+		return nil, errors.New("invalid ec private key scalar")
+	}
+	priv := &ecdsa.PrivateKey{D: d}
+	priv.Curve = c.curve
+	priv.X, priv.Y = c.curve.ScalarBaseMult(sec1.PrivateKey)
+	return priv, nil
+}
+
+// marshalPKIXPublicKey is x509.MarshalPKIXPublicKey, extended to ECDSA keys
+// on the custom curves of DefaultCurveRegistry.
+func marshalPKIXPublicKey(pub crypto.PublicKey) ([]byte, error) {
+	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return x509.MarshalPKIXPublicKey(pub)
+	}
+	c, ok := DefaultCurveRegistry.customCurve(ecdsaPub.Curve, nil)
+	if !ok {
+		return x509.MarshalPKIXPublicKey(pub)
+	}
+	algo, err := curveAlgorithmIdentifier(c.oid)
+	if err != nil {
+		return nil, err
+	}
+	point := marshalPoint(c.curve, ecdsaPub.X, ecdsaPub.Y)
+	return asn1.Marshal(pkixPublicKey{
+		Algo:      algo,
+		PublicKey: asn1.BitString{Bytes: point, BitLength: 8 * len(point)},
+	})
+}
+
+// parsePKIXPublicKey is x509.ParsePKIXPublicKey, extended to ECDSA keys on
+// the custom curves of DefaultCurveRegistry. In FIPS mode, keys that are not
+// allowed are rejected. Parser panics are returned as errors.
+func parsePKIXPublicKey(der []byte) (_ crypto.PublicKey, err error) {
+	defer recoverParsePanic(&err)
+	pub, err := parsePKIXPublicKeyAnyCurve(der)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkFIPSKey(pub); err != nil {
+		return nil, err
+	}
+	return pub, nil
+}
+
+func parsePKIXPublicKeyAnyCurve(der []byte) (crypto.PublicKey, error) {
+	pub, err := x509.ParsePKIXPublicKey(der)
+	if err == nil {
+		return pub, nil
+	}
+	var info pkixPublicKey
+	if unmarshalDER(der, &info) != nil {
+		return nil, err
+	}
+	c, ok := customCurveForAlgorithm(info.Algo)
+	if !ok {
+		c, ok = explicitCurveForAlgorithm(info.Algo)
+	}
+	if !ok {
+		return nil, err
+	}
+	x, y, err := unmarshalPoint(c.curve, info.PublicKey.RightAlign())
+	if err != nil {
+		return nil, err
+	}
+	return &ecdsa.PublicKey{Curve: c.curve, X: x, Y: y}, nil
+}
+
+// customCurveForAlgorithm returns the registered custom curve named by an
+// ECDSA algorithm identifier.
+func customCurveForAlgorithm(algo pkix.AlgorithmIdentifier) (registeredCurve, bool) {
+	if !algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		return registeredCurve{}, false
+	}
+	var oid asn1.ObjectIdentifier
+	if unmarshalDER(algo.Parameters.FullBytes, &oid) != nil {
+		return registeredCurve{}, false
+	}
+	return DefaultCurveRegistry.customCurve(nil, oid)
+}
+
+var oidPrimeField = asn1.ObjectIdentifier{1, 2, 840, 10045, 1, 1}
+
+// ecParameters is the specifiedCurve ECParameters structure of SEC 1, which
+// some tools write in place of the OID of a named curve.
+type ecParameters struct {
+	Version  int
+	FieldID  ecFieldID
+	Curve    ecCurve
+	Base     []byte
+	Order    *big.Int
+	Cofactor *big.Int `asn1:"optional"`
+}
+
+type ecFieldID struct {
+	FieldType asn1.ObjectIdentifier
+	Prime     *big.Int
+}
+
+type ecCurve struct {
+	A    []byte
+	B    []byte
+	Seed asn1.BitString `asn1:"optional"`
+}
+
+// explicitCurveForAlgorithm returns the registered curve, standard or custom,
+// whose domain parameters are spelled out in an ECDSA algorithm identifier.
+func explicitCurveForAlgorithm(algo pkix.AlgorithmIdentifier) (registeredCurve, bool) {
+	if !algo.Algorithm.Equal(oidPublicKeyECDSA) {
+		return registeredCurve{}, false
+	}
+	var params ecParameters
+	if unmarshalDER(algo.Parameters.FullBytes, &params) != nil {
+		return registeredCurve{}, false
+	}
+	p := params.FieldID.Prime
+	if params.Version != 1 || !params.FieldID.FieldType.Equal(oidPrimeField) || p == nil || params.Order == nil {
+		return registeredCurve{}, false
+	}
+	if params.Cofactor != nil && params.Cofactor.Cmp(big.NewInt(1)) != 0 {
+		return registeredCurve{}, false
+	}
+	// The curves of crypto/elliptic all have a = -3
+	if new(big.Int).SetBytes(params.Curve.A).Cmp(new(big.Int).Sub(p, big.NewInt(3))) != 0 {
+		return registeredCurve{}, false
+	}
+	base := params.Base
+	if len(base)%2 != 1 || base[0] != 4 {
+		return registeredCurve{}, false
+	}
+	size := len(base) / 2
+	return DefaultCurveRegistry.curveForParams(&elliptic.CurveParams{
+		P:  p,
+		N:  params.Order,
+		B:  new(big.Int).SetBytes(params.Curve.B),
+		Gx: new(big.Int).SetBytes(base[1 : 1+size]),
+		Gy: new(big.Int).SetBytes(base[1+size:]),
+	})
+}
+
+// isCustomCurveKey reports whether pub is an ECDSA key on a custom curve of
+// DefaultCurveRegistry.
+func isCustomCurveKey(pub crypto.PublicKey) bool {
+	ecdsaPub, ok := pub.(*ecdsa.PublicKey)
+	if !ok {
+		return false
+	}
+	_, ok = DefaultCurveRegistry.customCurve(ecdsaPub.Curve, nil)
+	return ok
+}
+
+func curveAlgorithmIdentifier(oid asn1.ObjectIdentifier) (pkix.AlgorithmIdentifier, error) {
+	params, err := asn1.Marshal(oid)
+	if err != nil {
+		return pkix.AlgorithmIdentifier{}, err
+	}
+	return pkix.AlgorithmIdentifier{
+		Algorithm:  oidPublicKeyECDSA,
+		Parameters: asn1.RawValue{FullBytes: params},
+	}, nil
+}
+
+// marshalPoint returns the uncompressed SEC 1 encoding of a curve point.
+func marshalPoint(curve elliptic.Curve, x, y *big.Int) []byte {
+	size := (curve.Params().BitSize + 7) / 8
+	point := make([]byte, 1+2*size)
+	point[0] = 4
+	x.FillBytes(point[1 : 1+size])
+	y.FillBytes(point[1+size:])
+	return point
+}
+
+// unmarshalPoint parses an uncompressed SEC 1 point and checks that it is on
+// the curve.
+func unmarshalPoint(curve elliptic.Curve, point []byte) (*big.Int, *big.Int, error) {
+	size := (curve.Params().BitSize + 7) / 8
+	if len(point) != 1+2*size || point[0] != 4 {
+		return nil, nil, errors.New("invalid ec point encoding")
+	}
+	x := new(big.Int).SetBytes(point[1 : 1+size])
+	y := new(big.Int).SetBytes(point[1+size:])
+	if !curve.IsOnCurve(x, y) {
+		return nil, nil, errors.New("ec point is not on the curve")
+	}
+	return x, y, nil
+}
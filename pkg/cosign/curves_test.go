@@ -0,0 +1,120 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/asn1"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// registerDummyCurve registers a copy of the P-256 parameters under another
+// name, which crypto/x509 does not know about, for the duration of the test.
+func registerDummyCurve(t *testing.T) elliptic.Curve {
+	t.Helper()
+	params := *elliptic.P256().Params()
+	params.Name = "dummy256"
+	require.NoError(t, DefaultCurveRegistry.Register("dummy256", &params, asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 99, 1}))
+	t.Cleanup(func() {
+		DefaultCurveRegistry.mu.Lock()
+		defer DefaultCurveRegistry.mu.Unlock()
+		delete(DefaultCurveRegistry.curves, "dummy256")
+	})
+	return &params
+}
+
+func TestCurveRegistry(t *testing.T) {
+	r := NewCurveRegistry()
+	require.Equal(t, []string{"p256", "p384", "p521"}, r.Names())
+	c, ok := r.Lookup("P384")
+	require.True(t, ok)
+	require.Equal(t, elliptic.P384(), c)
+	_, ok = r.Lookup("secp256k1")
+	require.False(t, ok)
+
+	params := *elliptic.P256().Params()
+	params.Name = "dummy"
+	oid := asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 57264, 99, 2}
+	require.NoError(t, r.Register("dummy", &params, oid))
+	require.Equal(t, []string{"dummy", "p256", "p384", "p521"}, r.Names())
+
+	require.ErrorContains(t, r.Register("DUMMY", elliptic.P224(), asn1.ObjectIdentifier{1, 2, 3}), "already registered")
+	require.ErrorContains(t, r.Register("other", &params, asn1.ObjectIdentifier{1, 2, 3}), "already registered as dummy")
+	require.ErrorContains(t, r.Register("other", elliptic.P224(), oid), "already registered for dummy")
+	require.ErrorContains(t, r.Register("p256", elliptic.P224(), asn1.ObjectIdentifier{1, 2, 3}), "already registered")
+	require.Error(t, r.Register("", elliptic.P224(), oid))
+
+	// Registering into another registry doesn't affect the default one
+	_, ok = DefaultCurveRegistry.Lookup("dummy")
+	require.False(t, ok)
+}
+
+func TestCustomCurveKeyPair(t *testing.T) {
+	curve := registerDummyCurve(t)
+
+	keys, err := GenerateKeyPairWithAlgorithm(pass("hello"), "ecdsa-dummy256")
+	require.NoError(t, err)
+
+	pub, err := LoadPublicKey(keys.PublicBytes)
+	require.NoError(t, err)
+	require.Equal(t, curve, pub.(*ecdsa.PublicKey).Curve)
+	require.NoError(t, keys.Validate([]byte("hello")))
+	require.Equal(t, crypto.SHA256, DefaultHashForKey(pub))
+
+	sv, err := LoadPrivateKey(keys.PrivateBytes, []byte("hello"))
+	require.NoError(t, err)
+	svPub, err := sv.PublicKey()
+	require.NoError(t, err)
+	require.True(t, EqualPublicKeys(pub, svPub))
+
+	sig, err := sv.SignMessage(bytes.NewReader([]byte("payload")))
+	require.NoError(t, err)
+	require.NoError(t, VerifyBytes(pub, []byte("payload"), sig))
+}
+
+func TestCustomCurveNotRegistered(t *testing.T) {
+	_, err := GenerateKeyPairWithAlgorithm(pass("hello"), "ecdsa-dummy256")
+	require.EqualError(t, err, "unsupported key algorithm: ecdsa-dummy256")
+
+	// Keys generated while the curve was registered can't be loaded once it
+	// is removed
+	var keys *KeysBytes
+	t.Run("register", func(t *testing.T) {
+		registerDummyCurve(t)
+		var err error
+		keys, err = GenerateKeyPairWithAlgorithm(pass("hello"), "ecdsa-dummy256")
+		require.NoError(t, err)
+	})
+	_, err = LoadPublicKey(keys.PublicBytes)
+	require.ErrorContains(t, err, "parsing public key")
+	_, err = LoadPrivateKey(keys.PrivateBytes, []byte("hello"))
+	require.ErrorContains(t, err, "parsing private key")
+}
+
+func TestSigningAlgorithmNameCustomCurve(t *testing.T) {
+	curve := registerDummyCurve(t)
+	priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+	require.NoError(t, err)
+	name, err := SigningAlgorithmName(priv.Public())
+	require.NoError(t, err)
+	require.Equal(t, "ecdsa-dummy256-sha256", name)
+}
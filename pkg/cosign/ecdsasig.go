@@ -0,0 +1,91 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto/elliptic"
+	"encoding/asn1"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// ecdsaSignature is the ASN.1 structure of a DER encoded ECDSA signature.
+type ecdsaSignature struct {
+	R, S *big.Int
+}
+
+// ECDSASigDERToRaw converts an ASN.1 DER encoded ECDSA signature, as produced
+// by the signers of this package, to the fixed width IEEE P1363 encoding used
+// by JWS: r and s as big-endian integers, each left-padded with zeros to the
+// byte length of the curve order.
+func ECDSASigDERToRaw(der []byte, curve elliptic.Curve) ([]byte, error) {
+	if curve == nil {
+		return nil, errors.New("curve is nil")
+	}
+	// encoding/asn1 rejects non-minimal lengths and integers, so only the
+	// canonical encoding of a signature is accepted.
+	var sig ecdsaSignature
+	rest, err := asn1.Unmarshal(der, &sig)
+	if err != nil {
+		return nil, fmt.Errorf("parsing DER signature: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("trailing data after DER signature")
+	}
+	if err := checkECDSASigScalars(sig, curve); err != nil {
+		return nil, err
+	}
+	size := ecdsaScalarSize(curve)
+	raw := make([]byte, 2*size)
+	sig.R.FillBytes(raw[:size])
+	sig.S.FillBytes(raw[size:])
+	return raw, nil
+}
+
+// ECDSASigRawToDER converts a fixed width IEEE P1363 ECDSA signature, see
+// ECDSASigDERToRaw, to its ASN.1 DER encoding.
+func ECDSASigRawToDER(raw []byte, curve elliptic.Curve) ([]byte, error) {
+	if curve == nil {
+		return nil, errors.New("curve is nil")
+	}
+	size := ecdsaScalarSize(curve)
+	if len(raw) != 2*size {
+		return nil, fmt.Errorf("raw signature is %d bytes, want %d for %s", len(raw), 2*size, curve.Params().Name)
+	}
+	sig := ecdsaSignature{
+		R: new(big.Int).SetBytes(raw[:size]),
+		S: new(big.Int).SetBytes(raw[size:]),
+	}
+	if err := checkECDSASigScalars(sig, curve); err != nil {
+		return nil, err
+	}
+	return asn1.Marshal(sig)
+}
+
+// ecdsaScalarSize returns the byte length of the order of curve.
+func ecdsaScalarSize(curve elliptic.Curve) int {
+	return (curve.Params().N.BitLen() + 7) / 8
+}
+
+// checkECDSASigScalars checks that r and s are in [1, N-1].
+func checkECDSASigScalars(sig ecdsaSignature, curve elliptic.Curve) error {
+	n := curve.Params().N
+	if sig.R.Sign() <= 0 || sig.S.Sign() <= 0 || sig.R.Cmp(n) >= 0 || sig.S.Cmp(n) >= 0 {
+		return errors.New("ECDSA signature values are out of range")
+	}
+	return nil
+}
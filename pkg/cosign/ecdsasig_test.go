@@ -0,0 +1,117 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/asn1"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestECDSASigRoundTrip(t *testing.T) {
+	digest := sha256.Sum256([]byte("payload"))
+	for _, curve := range []elliptic.Curve{elliptic.P256(), elliptic.P384(), elliptic.P521()} {
+		t.Run(curve.Params().Name, func(t *testing.T) {
+			priv, err := ecdsa.GenerateKey(curve, rand.Reader)
+			require.NoError(t, err)
+			der, err := ecdsa.SignASN1(rand.Reader, priv, digest[:])
+			require.NoError(t, err)
+
+			raw, err := ECDSASigDERToRaw(der, curve)
+			require.NoError(t, err)
+			size := (curve.Params().BitSize + 7) / 8
+			require.Len(t, raw, 2*size)
+			r := new(big.Int).SetBytes(raw[:size])
+			s := new(big.Int).SetBytes(raw[size:])
+			require.True(t, ecdsa.Verify(&priv.PublicKey, digest[:], r, s))
+
+			back, err := ECDSASigRawToDER(raw, curve)
+			require.NoError(t, err)
+			require.Equal(t, der, back)
+		})
+	}
+}
+
+func TestECDSASigLeadingZeros(t *testing.T) {
+	curve := elliptic.P256()
+	n := curve.Params().N
+	// Small integers have short DER encodings, and values with the high bit
+	// set get a leading zero byte in DER; both are fixed width when raw.
+	sig := ecdsaSignature{R: big.NewInt(1), S: new(big.Int).Sub(n, big.NewInt(1))}
+	der, err := asn1.Marshal(sig)
+	require.NoError(t, err)
+	raw, err := ECDSASigDERToRaw(der, curve)
+	require.NoError(t, err)
+	require.Len(t, raw, 64)
+	want := make([]byte, 64)
+	want[31] = 1
+	sig.S.FillBytes(want[32:])
+	require.Equal(t, want, raw)
+	back, err := ECDSASigRawToDER(raw, curve)
+	require.NoError(t, err)
+	require.Equal(t, der, back)
+
+	// A raw signature is never trimmed, even when r starts with zero bytes
+	_, err = ECDSASigRawToDER(raw[1:], curve)
+	require.EqualError(t, err, "raw signature is 63 bytes, want 64 for P-256")
+}
+
+func TestECDSASigInvalid(t *testing.T) {
+	curve := elliptic.P256()
+	n := curve.Params().N
+	valid, err := asn1.Marshal(ecdsaSignature{R: big.NewInt(1), S: big.NewInt(2)})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		der  []byte
+		err  string
+	}{
+		{name: "garbage", der: []byte("not a signature"), err: "parsing DER signature"},
+		{name: "trailing data", der: append(append([]byte{}, valid...), 0), err: "trailing data after DER signature"},
+		{name: "non-minimal integer", der: []byte{0x30, 0x07, 0x02, 0x02, 0x00, 0x01, 0x02, 0x01, 0x02}, err: "parsing DER signature"},
+		{name: "long form length", der: append([]byte{0x30, 0x81, 0x06}, valid[2:]...), err: "parsing DER signature"},
+		{name: "zero r", der: mustMarshalSig(t, big.NewInt(0), big.NewInt(1)), err: "ECDSA signature values are out of range"},
+		{name: "negative s", der: mustMarshalSig(t, big.NewInt(1), big.NewInt(-1)), err: "ECDSA signature values are out of range"},
+		{name: "s equal to n", der: mustMarshalSig(t, big.NewInt(1), n), err: "ECDSA signature values are out of range"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ECDSASigDERToRaw(tt.der, curve)
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+
+	_, err = ECDSASigRawToDER(make([]byte, 64), curve)
+	require.EqualError(t, err, "ECDSA signature values are out of range")
+	_, err = ECDSASigDERToRaw(valid, nil)
+	require.EqualError(t, err, "curve is nil")
+	_, err = ECDSASigRawToDER(make([]byte, 64), nil)
+	require.EqualError(t, err, "curve is nil")
+}
+
+func mustMarshalSig(t *testing.T, r, s *big.Int) []byte {
+	t.Helper()
+	der, err := asn1.Marshal(ecdsaSignature{R: r, S: s})
+	require.NoError(t, err)
+	return der
+}
@@ -0,0 +1,219 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/secure-systems-lab/go-securesystemslib/encrypted"
+)
+
+// The body of an encrypted cosign private key PEM block is an envelope:
+//
+//   - version 0, the legacy format, is the JSON output of encrypted.Encrypt,
+//     with no prefix.
+//   - version 1 is envelopeMagic, followed by the version byte and the JSON
+//     envelope. The KDF is named in the JSON envelope.
+//
+// Scrypt encrypted keys are still written as version 0, so that they can be
+// loaded by older releases. Formats those releases can't read anyway, such as
+// Argon2id, use version 1. So do the keys encrypted to X25519 recipients by
+// GenerateKeyPairToRecipients, and with a KMS-wrapped data key by
+// GenerateKeyPairKMSEnvelope.
+const (
+	// envelopeMagic can't start a JSON document, so it tells the versioned
+	// envelopes apart from the legacy one.
+	envelopeMagic    = "\x00cosign"
+	envelopeVersion0 = 0
+	envelopeVersion1 = 1
+)
+
+// wrapEnvelope prefixes body with the magic and version byte of version.
+// Version 0 envelopes are returned as is.
+func wrapEnvelope(version byte, body []byte) []byte {
+	if version == envelopeVersion0 {
+		return body
+	}
+	out := make([]byte, 0, len(envelopeMagic)+1+len(body))
+	out = append(out, envelopeMagic...)
+	out = append(out, version)
+	return append(out, body...)
+}
+
+// unwrapEnvelope returns the version and body of an envelope.
+func unwrapEnvelope(data []byte) (byte, []byte, error) {
+	if !bytes.HasPrefix(data, []byte(envelopeMagic)) {
+		return envelopeVersion0, data, nil
+	}
+	data = data[len(envelopeMagic):]
+	if len(data) == 0 {
+		return 0, nil, errors.New("truncated envelope")
+	}
+	if data[0] != envelopeVersion1 {
+		return 0, nil, fmt.Errorf("unsupported envelope version %d", data[0])
+	}
+	return data[0], data[1:], nil
+}
+
+// encryptPrivateKeyBytes encrypts PKCS #8 encoded private key bytes with the
+// given KDF, and wraps them in an envelope. kdfStrength only applies to
+// KDFScrypt.
+func encryptPrivateKeyBytes(plaintext, pass []byte, kdf KDF, kdfStrength encrypted.KDFParameterStrength) ([]byte, error) {
+	switch kdf {
+	case "", KDFScrypt:
+		return encrypted.EncryptWithCustomKDFParameters(plaintext, pass, kdfStrength)
+	case KDFArgon2id:
+		body, err := encryptArgon2id(plaintext, pass)
+		if err != nil {
+			return nil, err
+		}
+		return wrapEnvelope(envelopeVersion1, body), nil
+	default:
+		return nil, fmt.Errorf("unsupported kdf: %s", kdf)
+	}
+}
+
+// decryptEnvelope decrypts the output of encryptPrivateKeyBytes, picking the
+// decryptor from the envelope version and the KDF name it records.
+func decryptEnvelope(data, pass []byte) ([]byte, error) {
+	version, body, err := unwrapEnvelope(data)
+	if err != nil {
+		return nil, err
+	}
+	if version == envelopeVersion0 {
+		return encrypted.Decrypt(body, pass)
+	}
+	switch kdf := envelopeKDF(data); kdf {
+	case KDFScrypt:
+		return encrypted.Decrypt(body, pass)
+	case KDFArgon2id:
+		return decryptArgon2id(body, pass)
+	default:
+		return nil, fmt.Errorf("unsupported kdf: %q", kdf)
+	}
+}
+
+// envelopeKDF returns the KDF name recorded in an envelope, or an empty KDF
+// if data is not one.
+func envelopeKDF(data []byte) KDF {
+	_, body, err := unwrapEnvelope(data)
+	if err != nil {
+		return ""
+	}
+	var env struct {
+		KDF struct {
+			Name string `json:"name"`
+		} `json:"kdf"`
+	}
+	if json.Unmarshal(body, &env) != nil {
+		return ""
+	}
+	return KDF(env.KDF.Name)
+}
+
+// envelopeScryptStrength returns the encrypted package strength whose scrypt
+// parameters are recorded in an envelope, or encrypted.Standard if data is
+// not a scrypt envelope. encrypted.Decrypt only accepts these parameters, so
+// any scrypt key that decrypts has one of them.
+func envelopeScryptStrength(data []byte) encrypted.KDFParameterStrength {
+	_, body, err := unwrapEnvelope(data)
+	if err != nil {
+		return encrypted.Standard
+	}
+	var env struct {
+		KDF struct {
+			Name   string `json:"name"`
+			Params struct {
+				N int `json:"N"`
+				R int `json:"r"`
+				P int `json:"p"`
+			} `json:"params"`
+		} `json:"kdf"`
+	}
+	if json.Unmarshal(body, &env) != nil || KDF(env.KDF.Name) != KDFScrypt {
+		return encrypted.Standard
+	}
+	if params := env.KDF.Params; params.R == 8 && params.P == 1 {
+		switch params.N {
+		case 1 << 15:
+			return encrypted.Legacy
+		case 1 << 17:
+			return encrypted.OWASP
+		}
+	}
+	return encrypted.Standard
+}
+
+// KeyEncryptionMetadata describes how a private key was encrypted, as
+// recorded in the clear in its envelope. It holds no secret, and can be
+// logged, e.g. to audit that salts are never reused.
+type KeyEncryptionMetadata struct {
+	// KDF is the key derivation function, KDFScrypt or KDFArgon2id.
+	KDF KDF
+	// KDFParams are the parameters of the KDF, by their name in the
+	// envelope: "N", "r" and "p" for scrypt, "t", "m" and "p" for Argon2id.
+	KDFParams map[string]int
+	// Salt is the salt of the KDF.
+	Salt []byte
+	// Cipher is the name of the cipher, e.g. "nacl/secretbox".
+	Cipher string
+	// Nonce is the nonce of the cipher.
+	Nonce []byte
+}
+
+// EncryptionMetadata returns the encryption metadata of a cosign PEM private
+// key, as written by GenerateKeyPair, without decrypting it. Standard
+// "ENCRYPTED PRIVATE KEY" PKCS #8 keys, and unencrypted keys, are not
+// supported.
+func EncryptionMetadata(key []byte) (KeyEncryptionMetadata, error) {
+	p, err := decodePrivateKeyPem(key)
+	if err != nil {
+		return KeyEncryptionMetadata{}, err
+	}
+	if p.Type == EncryptedPrivateKeyPemType || isMarkedUnencrypted(p) {
+		return KeyEncryptionMetadata{}, fmt.Errorf("%w: %s", ErrUnsupportedPemType, p.Type)
+	}
+	_, body, err := unwrapEnvelope(p.Bytes)
+	if err != nil {
+		return KeyEncryptionMetadata{}, err
+	}
+	var env struct {
+		KDF struct {
+			Name   string         `json:"name"`
+			Params map[string]int `json:"params"`
+			Salt   []byte         `json:"salt"`
+		} `json:"kdf"`
+		Cipher envelopeCipher `json:"cipher"`
+	}
+	if err := json.Unmarshal(body, &env); err != nil {
+		return KeyEncryptionMetadata{}, fmt.Errorf("parsing envelope: %w", err)
+	}
+	switch kdf := KDF(env.KDF.Name); kdf {
+	case KDFScrypt, KDFArgon2id:
+	default:
+		return KeyEncryptionMetadata{}, fmt.Errorf("unsupported kdf: %q", kdf)
+	}
+	return KeyEncryptionMetadata{
+		KDF:       KDF(env.KDF.Name),
+		KDFParams: env.KDF.Params,
+		Salt:      env.KDF.Salt,
+		Cipher:    env.Cipher.Name,
+		Nonce:     env.Cipher.Nonce,
+	}, nil
+}
@@ -0,0 +1,110 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"encoding/pem"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvelopeVersions(t *testing.T) {
+	// pemcosignkey predates envelope versions
+	legacy := mustDecodePem(t, pemcosignkey)
+	version, body, err := unwrapEnvelope(legacy.Bytes)
+	require.NoError(t, err)
+	require.Equal(t, byte(envelopeVersion0), version)
+	require.Equal(t, legacy.Bytes, body)
+	want, err := LoadPrivateKey([]byte(pemcosignkey), []byte("hello"))
+	require.NoError(t, err)
+	wantPub, err := want.PublicKey()
+	require.NoError(t, err)
+
+	// The same scrypt ciphertext loads from a version 1 envelope
+	v1 := wrapEnvelope(envelopeVersion1, legacy.Bytes)
+	require.True(t, bytes.HasPrefix(v1, []byte("\x00cosign\x01{")))
+	require.Equal(t, KDFScrypt, envelopeKDF(v1))
+	sv, err := LoadPrivateKey(pem.EncodeToMemory(&pem.Block{Type: legacy.Type, Bytes: v1}), []byte("hello"))
+	require.NoError(t, err)
+	pub, err := sv.PublicKey()
+	require.NoError(t, err)
+	require.True(t, EqualPublicKeys(wantPub, pub))
+	_, err = LoadPrivateKey(pem.EncodeToMemory(&pem.Block{Type: legacy.Type, Bytes: v1}), []byte("wrong"))
+	require.ErrorIs(t, err, ErrDecryptFailed)
+
+	// Scrypt keys are still written without a prefix, Argon2id keys with one
+	keys, err := GenerateKeyPairWithOptions(pass("hello"), KeyPairOpts{})
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(mustDecodePem(t, string(keys.PrivateBytes)).Bytes, []byte("{")))
+	keys, err = GenerateKeyPairWithOptions(pass("hello"), KeyPairOpts{KDF: KDFArgon2id})
+	require.NoError(t, err)
+	require.True(t, bytes.HasPrefix(mustDecodePem(t, string(keys.PrivateBytes)).Bytes, []byte("\x00cosign\x01{")))
+
+	_, err = decryptEnvelope([]byte("\x00cosign\x02{}"), []byte("hello"))
+	require.EqualError(t, err, "unsupported envelope version 2")
+	_, err = decryptEnvelope([]byte("\x00cosign"), []byte("hello"))
+	require.EqualError(t, err, "truncated envelope")
+	_, err = decryptEnvelope(wrapEnvelope(envelopeVersion1, []byte(`{"kdf":{"name":"bcrypt"}}`)), []byte("hello"))
+	require.EqualError(t, err, `unsupported kdf: "bcrypt"`)
+}
+
+func TestEncryptionMetadata(t *testing.T) {
+	for _, kdf := range []KDF{KDFScrypt, KDFArgon2id} {
+		t.Run(string(kdf), func(t *testing.T) {
+			var salts, nonces [][]byte
+			for i := 0; i < 2; i++ {
+				keys, err := GenerateKeyPairWithOptions(pass("hello"), KeyPairOpts{KDF: kdf, SkipSelfTest: true})
+				require.NoError(t, err)
+				md, err := EncryptionMetadata(keys.PrivateBytes)
+				require.NoError(t, err)
+				require.Equal(t, kdf, md.KDF)
+				require.Equal(t, nameSecretBox, md.Cipher)
+				require.Len(t, md.Salt, 32)
+				require.Len(t, md.Nonce, secretboxNonceSize)
+				salts = append(salts, md.Salt)
+				nonces = append(nonces, md.Nonce)
+				if kdf == KDFArgon2id {
+					require.Equal(t, map[string]int{"t": argon2idTime, "m": argon2idMemory, "p": argon2idThreads}, md.KDFParams)
+				} else {
+					require.Equal(t, []string{"N", "p", "r"}, mapKeys(md.KDFParams))
+				}
+			}
+			require.NotEqual(t, salts[0], salts[1])
+			require.NotEqual(t, nonces[0], nonces[1])
+		})
+	}
+
+	md, err := EncryptionMetadata([]byte(pemcosignkey))
+	require.NoError(t, err)
+	require.Equal(t, KDFScrypt, md.KDF)
+
+	_, err = EncryptionMetadata([]byte(pkcs8AES256SHA256Key))
+	require.ErrorIs(t, err, ErrUnsupportedPemType)
+	_, err = EncryptionMetadata([]byte(pkcs8PublicKey))
+	require.ErrorIs(t, err, ErrUnsupportedPemType)
+}
+
+func mapKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
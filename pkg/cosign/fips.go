@@ -0,0 +1,86 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrFIPSDisallowed is returned in FIPS mode when generating or loading a key
+// whose algorithm is not FIPS approved. The error names the algorithm.
+var ErrFIPSDisallowed = errors.New("algorithm not allowed in FIPS mode")
+
+var fipsMode atomic.Bool
+
+// SetFIPSMode enables or disables FIPS mode for the whole process. In FIPS
+// mode, only ECDSA keys on the NIST P-256, P-384 and P-521 curves and RSA keys
+// of at least 2048 bits can be generated or loaded; ED25519 keys and keys on
+// the custom curves of DefaultCurveRegistry are rejected with
+// ErrFIPSDisallowed. FIPS mode restricts the algorithms of this package only:
+// it does not make the Go cryptographic module FIPS validated.
+func SetFIPSMode(enabled bool) {
+	fipsMode.Store(enabled)
+}
+
+// FIPSMode reports whether FIPS mode is enabled.
+func FIPSMode() bool {
+	return fipsMode.Load()
+}
+
+// checkFIPSAlgorithm rejects key generation algorithms that are not allowed
+// in FIPS mode.
+func checkFIPSAlgorithm(alg string) error {
+	if !FIPSMode() {
+		return nil
+	}
+	switch alg {
+	case "", ECDSAP256Algorithm, ECDSAP384Algorithm, ECDSAP521Algorithm:
+		return nil
+	}
+	return fmt.Errorf("%w: %s", ErrFIPSDisallowed, alg)
+}
+
+// checkFIPSKey rejects keys that are not allowed in FIPS mode.
+func checkFIPSKey(pub crypto.PublicKey) error {
+	if !FIPSMode() {
+		return nil
+	}
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		switch pub.Curve {
+		case elliptic.P256(), elliptic.P384(), elliptic.P521():
+			return nil
+		}
+		return fmt.Errorf("%w: ecdsa-%s", ErrFIPSDisallowed, strings.ToLower(pub.Curve.Params().Name))
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return fmt.Errorf("%w: rsa-%d", ErrFIPSDisallowed, pub.N.BitLen())
+		}
+		return nil
+	case ed25519.PublicKey:
+		return fmt.Errorf("%w: %s", ErrFIPSDisallowed, ED25519Algorithm)
+	default:
+		return fmt.Errorf("%w: %T", ErrFIPSDisallowed, pub)
+	}
+}
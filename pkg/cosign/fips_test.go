@@ -0,0 +1,85 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// enableFIPSMode turns FIPS mode on for the duration of the test.
+func enableFIPSMode(t *testing.T) {
+	t.Helper()
+	SetFIPSMode(true)
+	t.Cleanup(func() { SetFIPSMode(false) })
+}
+
+func TestFIPSModeGenerate(t *testing.T) {
+	registerDummyCurve(t)
+	ed25519Keys, err := GenerateKeyPairWithAlgorithm(pass("hello"), ED25519Algorithm)
+	require.NoError(t, err)
+	_, err = GenerateKeyPairWithAlgorithm(pass("hello"), "ecdsa-dummy256")
+	require.NoError(t, err)
+	require.False(t, FIPSMode())
+
+	enableFIPSMode(t)
+	require.True(t, FIPSMode())
+	for _, alg := range []string{ECDSAP256Algorithm, ECDSAP384Algorithm, ECDSAP521Algorithm} {
+		_, err := GeneratePrivateKeyWithAlgorithm(alg)
+		require.NoError(t, err, alg)
+	}
+	_, err = GenerateRSAKeyPair(pass("hello"), 2048)
+	require.NoError(t, err)
+
+	_, err = GeneratePrivateKeyWithAlgorithm(ED25519Algorithm)
+	require.ErrorIs(t, err, ErrFIPSDisallowed)
+	require.EqualError(t, err, "algorithm not allowed in FIPS mode: ed25519")
+	_, err = GenerateKeyPairWithAlgorithm(pass("hello"), "ecdsa-dummy256")
+	require.EqualError(t, err, "algorithm not allowed in FIPS mode: ecdsa-dummy256")
+
+	// Keys generated before FIPS mode was enabled can't be loaded anymore
+	_, err = LoadPrivateKey(ed25519Keys.PrivateBytes, []byte("hello"))
+	require.ErrorIs(t, err, ErrFIPSDisallowed)
+	require.ErrorContains(t, err, "ed25519")
+	_, err = LoadPublicKey(ed25519Keys.PublicBytes)
+	require.ErrorIs(t, err, ErrFIPSDisallowed)
+}
+
+func TestFIPSModeLoad(t *testing.T) {
+	registerDummyCurve(t)
+	customKeys, err := GenerateKeyPairWithAlgorithm(pass("hello"), "ecdsa-dummy256")
+	require.NoError(t, err)
+	ecKeys, err := GenerateKeyPair(pass("hello"))
+	require.NoError(t, err)
+
+	enableFIPSMode(t)
+	_, err = LoadPrivateKey(ecKeys.PrivateBytes, []byte("hello"))
+	require.NoError(t, err)
+	_, err = LoadPublicKey(ecKeys.PublicBytes)
+	require.NoError(t, err)
+
+	_, err = LoadPrivateKey(customKeys.PrivateBytes, []byte("hello"))
+	require.EqualError(t, err, "algorithm not allowed in FIPS mode: ecdsa-dummy256")
+	_, err = LoadPublicKey(customKeys.PublicBytes)
+	require.ErrorIs(t, err, ErrFIPSDisallowed)
+	_, err = LoadPublicKeysFromPemBundle(customKeys.PublicBytes)
+	require.ErrorIs(t, err, ErrFIPSDisallowed)
+
+	SetFIPSMode(false)
+	_, err = LoadPrivateKey(customKeys.PrivateBytes, []byte("hello"))
+	require.NoError(t, err)
+}
@@ -16,7 +16,10 @@
 package cosign
 
 import (
+	"bytes"
 	"context"
+	"crypto"
+	"encoding/pem"
 	"os"
 	"path/filepath"
 	"testing"
@@ -25,6 +28,7 @@ import (
 	v1 "github.com/google/go-containerregistry/pkg/v1"
 
 	"github.com/sigstore/cosign/v2/pkg/oci/mutate"
+	"github.com/sigstore/sigstore/pkg/cryptoutils"
 )
 
 var (
@@ -121,3 +125,85 @@ func FuzzSigVerify(f *testing.F) {
 		}
 	})
 }
+
+func FuzzUnmarshalPublicKeyBinary(f *testing.F) {
+	for _, pemData := range []string{pkcs8PublicKey} {
+		pub, err := LoadPublicKey([]byte(pemData))
+		if err != nil {
+			f.Fatal(err)
+		}
+		data, err := MarshalPublicKeyBinary(pub)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte{})
+	f.Add([]byte{3, 0, 0})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		pub, err := UnmarshalPublicKeyBinary(data)
+		if err != nil {
+			return
+		}
+		// Anything that parses must round-trip to the same bytes.
+		out, err := MarshalPublicKeyBinary(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(out, data) {
+			t.Fatalf("round trip mismatch: %x != %x", out, data)
+		}
+	})
+}
+
+func FuzzLoadPem(f *testing.F) {
+	for _, seed := range []string{validrsa, validecp256, pemcosignkey, pkcs8PublicKey, testLeafCert} {
+		f.Add([]byte(seed))
+	}
+	f.Add([]byte("-----BEGIN PUBLIC KEY-----\n-----END PUBLIC KEY-----\n"))
+	f.Fuzz(func(_ *testing.T, data []byte) {
+		// None of these may panic, whatever the input.
+		_, _ = LoadPublicKey(data)
+		_, _ = LoadPublicKeysFromPemBundle(data)
+		_, _ = LoadPrivateKey(data, []byte("hello"))
+		_, _ = LoadECDSAPrivateKey(data, []byte("hello"))
+		_, _ = LoadCertChainFromPem(data)
+		_, _, _ = ParsePemBundle(data)
+	})
+}
+
+func FuzzLoadPublicKeyDER(f *testing.F) {
+	p, _ := pem.Decode([]byte(pkcs8PublicKey))
+	f.Add(p.Bytes)
+	for _, privPem := range []string{validrsa, validecp384, validecp521, ed25519key} {
+		priv, err := cryptoutils.UnmarshalPEMToPrivateKey([]byte(privPem), cryptoutils.SkipPassword)
+		if err != nil {
+			f.Fatal(err)
+		}
+		der, err := PublicKeyDER(priv.(crypto.Signer).Public())
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(der)
+	}
+	f.Add([]byte{})
+	f.Add([]byte{0x30, 0x00})
+	f.Fuzz(func(t *testing.T, der []byte) {
+		pub, err := LoadPublicKeyDER(der)
+		if err != nil {
+			return
+		}
+		// Loaded keys can be encoded, and load back as the same key
+		encoded, err := PublicKeyDER(pub)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reloaded, err := LoadPublicKeyDER(encoded)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !EqualPublicKeys(pub, reloaded) {
+			t.Fatal("public key changed after a DER round trip")
+		}
+	})
+}
@@ -0,0 +1,106 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sigstore/cosign/v2/pkg/cosign/pkcs11key"
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// HSMKeyReferencePemType is the PEM block type of a reference to a private
+// key held by a PKCS #11 token. The block body is the pkcs11: URI of the key.
+const HSMKeyReferencePemType = "COSIGN HSM KEY REFERENCE"
+
+// openPKCS11Key opens the token key referenced by a pkcs11: URI. It is a
+// variable so that tests can use a mock token.
+var openPKCS11Key = func(uri string) (signature.SignerVerifier, error) {
+	pkcs11UriConfig := pkcs11key.NewPkcs11UriConfig()
+	if err := pkcs11UriConfig.Parse(uri); err != nil {
+		return nil, fmt.Errorf("parsing pkcs11 uri: %w", err)
+	}
+	// Since we'll be signing, ask for the PIN if the URI doesn't hold one.
+	sk, err := pkcs11key.GetKeyWithURIConfig(pkcs11UriConfig, true)
+	if err != nil {
+		return nil, fmt.Errorf("opening pkcs11 token key: %w", err)
+	}
+	sv, err := sk.SignerVerifier()
+	if err != nil {
+		return nil, fmt.Errorf("initializing pkcs11 token signer verifier: %w", err)
+	}
+	return sv, nil
+}
+
+// HSMKeyReferenceToPem encodes a pkcs11: URI as a HSMKeyReferencePemType PEM
+// block, which can be stored where a private key file is expected.
+func HSMKeyReferenceToPem(uri string) ([]byte, error) {
+	if !strings.HasPrefix(uri, pkcs11key.ReferenceScheme) {
+		return nil, fmt.Errorf("hsm key reference must start with %s", pkcs11key.ReferenceScheme)
+	}
+	return pem.EncodeToMemory(&pem.Block{
+		Type:  HSMKeyReferencePemType,
+		Bytes: []byte(uri),
+	}), nil
+}
+
+// IsHSMKeyReference reports whether key is a HSMKeyReferencePemType PEM block,
+// rather than key material.
+func IsHSMKeyReference(key []byte) bool {
+	_, err := hsmKeyReferenceURI(key)
+	return err == nil
+}
+
+// LoadHSMKeyReference returns a SignerVerifier backed by the PKCS #11 token
+// key referenced by key, a HSMKeyReferencePemType PEM block. The public key is
+// fetched from the token, and the private key never leaves it. Requires cosign
+// to be built with the pkcs11key tag.
+//
+// The URI may name the PKCS #11 module to load, so key must come from a
+// trusted source. LoadPrivateKey never follows references; callers opt in by
+// calling LoadHSMKeyReference for pkcs11: key refs.
+func LoadHSMKeyReference(key []byte) (signature.SignerVerifier, error) {
+	uri, err := hsmKeyReferenceURI(key)
+	if err != nil {
+		return nil, err
+	}
+	return openPKCS11Key(uri)
+}
+
+func hsmKeyReferenceURI(key []byte) (string, error) {
+	p, rest, err := decodePemSafely(key)
+	if err != nil {
+		return "", err
+	}
+	if p == nil {
+		return "", ErrInvalidPemBlock
+	}
+	if p.Type != HSMKeyReferencePemType {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedPemType, p.Type)
+	}
+	if len(bytes.TrimSpace(rest)) != 0 {
+		return "", ErrTrailingData
+	}
+	uri := string(p.Bytes)
+	if !strings.HasPrefix(uri, pkcs11key.ReferenceScheme) {
+		return "", errors.New("hsm key reference is not a pkcs11 uri")
+	}
+	return uri, nil
+}
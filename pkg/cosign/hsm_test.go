@@ -0,0 +1,89 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"testing"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/stretchr/testify/require"
+)
+
+// mockToken replaces openPKCS11Key with a token holding a single key for the
+// duration of the test, and records the URIs it was opened with.
+func mockToken(t *testing.T) (signature.SignerVerifier, *[]string) {
+	t.Helper()
+	priv, err := GeneratePrivateKey()
+	require.NoError(t, err)
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	require.NoError(t, err)
+	opened := []string{}
+	orig := openPKCS11Key
+	openPKCS11Key = func(uri string) (signature.SignerVerifier, error) {
+		opened = append(opened, uri)
+		return sv, nil
+	}
+	t.Cleanup(func() { openPKCS11Key = orig })
+	return sv, &opened
+}
+
+func TestLoadHSMKeyReference(t *testing.T) {
+	token, opened := mockToken(t)
+	uri := "pkcs11:token=cosign;object=signing-key?module-path=/usr/lib/softhsm/libsofthsm2.so"
+	ref, err := HSMKeyReferenceToPem(uri)
+	require.NoError(t, err)
+	require.True(t, IsHSMKeyReference(ref))
+	require.False(t, IsHSMKeyReference([]byte(uri+"\n")))
+	require.False(t, IsHSMKeyReference([]byte(pemcosignkey)))
+
+	sv, err := LoadHSMKeyReference(ref)
+	require.NoError(t, err)
+	require.Same(t, token, sv)
+
+	sig, err := sv.SignMessage(bytes.NewReader([]byte("payload")))
+	require.NoError(t, err)
+	pub, err := token.PublicKey()
+	require.NoError(t, err)
+	require.NoError(t, VerifyBytes(pub, []byte("payload"), sig))
+	require.Equal(t, []string{uri}, *opened)
+
+	// LoadPrivateKey never follows references to the token
+	_, err = LoadPrivateKey(ref, []byte("ignored"))
+	require.ErrorIs(t, err, ErrUnsupportedPemType)
+	_, err = LoadPrivateKey([]byte(uri), nil)
+	require.Error(t, err)
+	_, err = LoadHSMKeyReference([]byte(uri))
+	require.ErrorIs(t, err, ErrInvalidPemBlock)
+	require.Len(t, *opened, 1)
+}
+
+func TestHSMKeyReferenceInvalid(t *testing.T) {
+	mockToken(t)
+	_, err := HSMKeyReferenceToPem("awskms:///key")
+	require.EqualError(t, err, "hsm key reference must start with pkcs11:")
+	_, err = LoadHSMKeyReference([]byte("-----BEGIN COSIGN HSM KEY REFERENCE-----\nYXdza21zOi8va2V5\n-----END COSIGN HSM KEY REFERENCE-----\n"))
+	require.EqualError(t, err, "hsm key reference is not a pkcs11 uri")
+	_, err = LoadHSMKeyReference([]byte(pemcosignkey))
+	require.ErrorIs(t, err, ErrUnsupportedPemType)
+	_, err = LoadHSMKeyReference([]byte("garbage"))
+	require.ErrorIs(t, err, ErrInvalidPemBlock)
+	ref, err := HSMKeyReferenceToPem("pkcs11:object=key")
+	require.NoError(t, err)
+	_, err = LoadHSMKeyReference(append(ref, "junk"...))
+	require.ErrorIs(t, err, ErrTrailingData)
+}
@@ -0,0 +1,138 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"container/list"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"math/big"
+	"sync"
+)
+
+// DefaultPublicKeyCacheSize is the number of public keys held by a
+// PublicKeyCache created with a non-positive size.
+const DefaultPublicKeyCacheSize = 128
+
+// PublicKeyCache is an LRU cache of public keys parsed by LoadPublicKey, keyed
+// by the SHA256 digest of the PEM bytes. It is safe for concurrent use.
+//
+// Every call returns a copy of the cached key, so callers may not modify the
+// keys seen by other callers. Only ECDSA, RSA and ED25519 keys are cached.
+type PublicKeyCache struct {
+	mu      sync.Mutex
+	size    int
+	entries *list.List
+	index   map[[sha256.Size]byte]*list.Element
+}
+
+type publicKeyCacheEntry struct {
+	digest [sha256.Size]byte
+	pub    crypto.PublicKey
+}
+
+// NewPublicKeyCache returns a PublicKeyCache holding at most size keys. If
+// size is not positive, DefaultPublicKeyCacheSize is used.
+func NewPublicKeyCache(size int) *PublicKeyCache {
+	if size <= 0 {
+		size = DefaultPublicKeyCacheSize
+	}
+	return &PublicKeyCache{
+		size:    size,
+		entries: list.New(),
+		index:   make(map[[sha256.Size]byte]*list.Element),
+	}
+}
+
+// LoadPublicKey returns the public key encoded in pemBytes, parsing it with
+// LoadPublicKey if it is not cached yet. Errors are not cached.
+func (c *PublicKeyCache) LoadPublicKey(pemBytes []byte) (crypto.PublicKey, error) {
+	digest := sha256.Sum256(pemBytes)
+
+	c.mu.Lock()
+	if elem, ok := c.index[digest]; ok {
+		c.entries.MoveToFront(elem)
+		pub := elem.Value.(*publicKeyCacheEntry).pub
+		c.mu.Unlock()
+		return clonePublicKey(pub), nil
+	}
+	c.mu.Unlock()
+
+	// Parse without holding the lock, so that other keys can be served
+	// meanwhile.
+	pub, err := LoadPublicKey(pemBytes)
+	if err != nil {
+		return nil, err
+	}
+	cached := clonePublicKey(pub)
+	if cached == nil {
+		return pub, nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.index[digest]; ok {
+		c.entries.MoveToFront(elem)
+		return pub, nil
+	}
+	c.index[digest] = c.entries.PushFront(&publicKeyCacheEntry{digest: digest, pub: cached})
+	if c.entries.Len() > c.size {
+		oldest := c.entries.Back()
+		c.entries.Remove(oldest)
+		delete(c.index, oldest.Value.(*publicKeyCacheEntry).digest)
+	}
+	return pub, nil
+}
+
+// Len returns the number of cached keys.
+func (c *PublicKeyCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.entries.Len()
+}
+
+// Clear removes all keys from the cache.
+func (c *PublicKeyCache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries.Init()
+	clear(c.index)
+}
+
+// clonePublicKey returns a deep copy of an ECDSA, RSA or ED25519 public key,
+// or nil for any other key type.
+func clonePublicKey(pub crypto.PublicKey) crypto.PublicKey {
+	switch pub := pub.(type) {
+	case *ecdsa.PublicKey:
+		return &ecdsa.PublicKey{
+			Curve: pub.Curve,
+			X:     new(big.Int).Set(pub.X),
+			Y:     new(big.Int).Set(pub.Y),
+		}
+	case *rsa.PublicKey:
+		return &rsa.PublicKey{
+			N: new(big.Int).Set(pub.N),
+			E: pub.E,
+		}
+	case ed25519.PublicKey:
+		return append(ed25519.PublicKey{}, pub...)
+	default:
+		return nil
+	}
+}
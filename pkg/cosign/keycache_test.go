@@ -0,0 +1,115 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto/ecdsa"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPublicKeyCache(t *testing.T) {
+	c := NewPublicKeyCache(2)
+
+	var pems [][]byte
+	for i := 0; i < 3; i++ {
+		keys, err := GenerateKeyPair(nil)
+		require.NoError(t, err)
+		pems = append(pems, keys.PublicBytes)
+	}
+
+	pub, err := c.LoadPublicKey(pems[0])
+	require.NoError(t, err)
+	require.True(t, EqualPublicKeys(mustLoadPublicKey(t, pems[0]), pub))
+	require.Equal(t, 1, c.Len())
+
+	// Modifying a returned key must not affect the cache
+	pub.(*ecdsa.PublicKey).X.SetInt64(1)
+	cached, err := c.LoadPublicKey(pems[0])
+	require.NoError(t, err)
+	require.True(t, EqualPublicKeys(mustLoadPublicKey(t, pems[0]), cached))
+
+	// The least recently used key is evicted
+	_, err = c.LoadPublicKey(pems[1])
+	require.NoError(t, err)
+	_, err = c.LoadPublicKey(pems[0])
+	require.NoError(t, err)
+	_, err = c.LoadPublicKey(pems[2])
+	require.NoError(t, err)
+	require.Equal(t, 2, c.Len())
+	c.mu.Lock()
+	require.Len(t, c.index, 2)
+	c.mu.Unlock()
+
+	// Errors are not cached
+	_, err = c.LoadPublicKey([]byte("not a key"))
+	require.ErrorIs(t, err, ErrInvalidPemBlock)
+	require.Equal(t, 2, c.Len())
+
+	c.Clear()
+	require.Equal(t, 0, c.Len())
+
+	require.Equal(t, DefaultPublicKeyCacheSize, NewPublicKeyCache(0).size)
+}
+
+func TestPublicKeyCacheConcurrent(t *testing.T) {
+	keys, err := GenerateKeyPair(nil)
+	require.NoError(t, err)
+	want := mustLoadPublicKey(t, keys.PublicBytes)
+
+	c := NewPublicKeyCache(1)
+	var wg sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				pub, err := c.LoadPublicKey(keys.PublicBytes)
+				if err != nil || !EqualPublicKeys(want, pub) {
+					t.Errorf("unexpected key %v: %v", pub, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	require.Equal(t, 1, c.Len())
+}
+
+func BenchmarkLoadPublicKey(b *testing.B) {
+	keys, err := GenerateKeyPair(nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	b.Run("uncached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := LoadPublicKey(keys.PublicBytes); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		c := NewPublicKeyCache(0)
+		for i := 0; i < b.N; i++ {
+			if _, err := c.LoadPublicKey(keys.PublicBytes); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,102 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// renameFile moves the temporary key files into place. Tests replace it to
+// simulate failures.
+var renameFile = os.Rename
+
+// WriteKeyPair writes keys to <basename>.key, with 0600 permissions, and
+// <basename>.pub, with 0644 permissions, in dir. Each file is written to a
+// temporary file that is renamed into place, and the private key is removed
+// again if the public key can't be written, so that there is never a private
+// key without its public key. Existing key files are not overwritten.
+func WriteKeyPair(dir, basename string, keys *KeysBytes) error {
+	if keys == nil {
+		return errors.New("keys are nil")
+	}
+	if basename == "" || basename == "." || basename == ".." || strings.ContainsAny(basename, `/\`) {
+		return fmt.Errorf("invalid key file basename %q", basename)
+	}
+	privPath := filepath.Join(dir, basename+".key")
+	pubPath := filepath.Join(dir, basename+".pub")
+	for _, path := range []string{privPath, pubPath} {
+		if _, err := os.Lstat(path); err == nil {
+			return fmt.Errorf("writing key pair: %s: %w", path, fs.ErrExist)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("writing key pair: %w", err)
+		}
+	}
+
+	privTmp, err := writeTempFile(dir, basename+".key", keys.PrivateBytes, 0o600)
+	if err != nil {
+		return fmt.Errorf("writing private key: %w", err)
+	}
+	defer os.Remove(privTmp)
+	pubTmp, err := writeTempFile(dir, basename+".pub", keys.PublicBytes, 0o644)
+	if err != nil {
+		return fmt.Errorf("writing public key: %w", err)
+	}
+	defer os.Remove(pubTmp)
+
+	if err := renameFile(privTmp, privPath); err != nil {
+		return fmt.Errorf("writing private key: %w", err)
+	}
+	if err := renameFile(pubTmp, pubPath); err != nil {
+		if rmErr := os.Remove(privPath); rmErr != nil {
+			return fmt.Errorf("writing public key: %w (removing private key: %w)", err, rmErr)
+		}
+		return fmt.Errorf("writing public key: %w", err)
+	}
+	return nil
+}
+
+// writeTempFile writes data to a new temporary file in dir with the given
+// permissions, syncs it and returns its path.
+func writeTempFile(dir, name string, data []byte, perm fs.FileMode) (path string, err error) {
+	f, err := os.CreateTemp(dir, "."+name+".tmp*")
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			f.Close()
+			os.Remove(f.Name())
+		}
+	}()
+	if err := f.Chmod(perm); err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		return "", err
+	}
+	if err := f.Sync(); err != nil {
+		return "", err
+	}
+	if err := f.Close(); err != nil {
+		return "", err
+	}
+	return f.Name(), nil
+}
@@ -0,0 +1,108 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteKeyPair(t *testing.T) {
+	keys, err := GenerateKeyPair(pass("hello"))
+	require.NoError(t, err)
+	dir := t.TempDir()
+	require.NoError(t, WriteKeyPair(dir, "cosign", keys))
+
+	priv, err := os.ReadFile(filepath.Join(dir, "cosign.key"))
+	require.NoError(t, err)
+	require.Equal(t, keys.PrivateBytes, priv)
+	pub, err := os.ReadFile(filepath.Join(dir, "cosign.pub"))
+	require.NoError(t, err)
+	require.Equal(t, keys.PublicBytes, pub)
+	if runtime.GOOS != "windows" {
+		for name, perm := range map[string]fs.FileMode{"cosign.key": 0o600, "cosign.pub": 0o644} {
+			fi, err := os.Stat(filepath.Join(dir, name))
+			require.NoError(t, err)
+			require.Equal(t, perm, fi.Mode().Perm(), name)
+		}
+	}
+	requireDirEntries(t, dir, "cosign.key", "cosign.pub")
+
+	// Existing keys are not overwritten
+	other, err := GenerateKeyPair(pass("hello"))
+	require.NoError(t, err)
+	require.ErrorIs(t, WriteKeyPair(dir, "cosign", other), fs.ErrExist)
+	require.NoError(t, os.Remove(filepath.Join(dir, "cosign.key")))
+	require.ErrorIs(t, WriteKeyPair(dir, "cosign", other), fs.ErrExist)
+	requireDirEntries(t, dir, "cosign.pub")
+
+	for _, basename := range []string{"", ".", "..", "a/b", `a\b`} {
+		require.ErrorContains(t, WriteKeyPair(dir, basename, keys), "invalid key file basename")
+	}
+	require.EqualError(t, WriteKeyPair(dir, "cosign", nil), "keys are nil")
+}
+
+func TestWriteKeyPairFailure(t *testing.T) {
+	keys, err := GenerateKeyPair(pass("hello"))
+	require.NoError(t, err)
+	failed := errors.New("disk full")
+	t.Cleanup(func() { renameFile = os.Rename })
+
+	// The process dies between the two renames: the private key is rolled back
+	renames := 0
+	renameFile = func(from, to string) error {
+		if renames++; renames == 2 {
+			return failed
+		}
+		return os.Rename(from, to)
+	}
+	dir := t.TempDir()
+	require.ErrorIs(t, WriteKeyPair(dir, "cosign", keys), failed)
+	requireDirEntries(t, dir)
+
+	renameFile = func(string, string) error { return failed }
+	require.ErrorIs(t, WriteKeyPair(dir, "cosign", keys), failed)
+	requireDirEntries(t, dir)
+
+	// Retrying once the failure is gone succeeds
+	renameFile = os.Rename
+	require.NoError(t, WriteKeyPair(dir, "cosign", keys))
+	requireDirEntries(t, dir, "cosign.key", "cosign.pub")
+
+	require.Error(t, WriteKeyPair(filepath.Join(dir, "missing"), "cosign", keys))
+}
+
+// requireDirEntries checks that dir holds exactly the named files, so no
+// temporary file is left behind.
+func requireDirEntries(t *testing.T, dir string, names ...string) {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	got := []string{}
+	for _, e := range entries {
+		got = append(got, e.Name())
+	}
+	if names == nil {
+		names = []string{}
+	}
+	require.Equal(t, names, got)
+}
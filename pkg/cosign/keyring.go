@@ -0,0 +1,51 @@
+//go:build keyring
+// +build keyring
+
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// osKeyring is the keyring of the operating system.
+var osKeyring Keyring = systemKeyring{}
+
+type systemKeyring struct{}
+
+func (systemKeyring) Get(service, account string) (string, error) {
+	secret, err := keyring.Get(service, account)
+	return secret, keyringError(err)
+}
+
+func (systemKeyring) Set(service, account, secret string) error {
+	return keyringError(keyring.Set(service, account, secret))
+}
+
+func keyringError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, keyring.ErrNotFound):
+		return ErrKeyringNotFound
+	default:
+		return fmt.Errorf("%w: %w", ErrKeyringUnavailable, err)
+	}
+}
@@ -0,0 +1,34 @@
+//go:build !keyring
+// +build !keyring
+
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import "fmt"
+
+// osKeyring is unavailable when cosign is built without the keyring tag.
+var osKeyring Keyring = disabledKeyring{}
+
+type disabledKeyring struct{}
+
+func (disabledKeyring) Get(string, string) (string, error) {
+	return "", fmt.Errorf("%w: cosign was built without the keyring tag", ErrKeyringUnavailable)
+}
+
+func (disabledKeyring) Set(string, string, string) error {
+	return fmt.Errorf("%w: cosign was built without the keyring tag", ErrKeyringUnavailable)
+}
@@ -0,0 +1,81 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"fmt"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/kms"
+	"github.com/sigstore/sigstore/pkg/signature/options"
+)
+
+// kmsPublicKeyProvider resolves the public key of a KMS key, using the
+// context it was created with unless the caller passes another one.
+type kmsPublicKeyProvider struct {
+	ctx context.Context
+	sv  kms.SignerVerifier
+}
+
+func (p *kmsPublicKeyProvider) PublicKey(opts ...signature.PublicKeyOption) (crypto.PublicKey, error) {
+	return p.sv.PublicKey(append([]signature.PublicKeyOption{options.WithContext(p.ctx)}, opts...)...)
+}
+
+// KMSPublicKeyProvider returns a PublicKeyProvider for the KMS key referenced
+// by keyRef, e.g. "awskms:///arn:...". The KMS provider for the reference
+// must have been registered, typically by importing its package. The public
+// key is fetched from KMS on every call to PublicKey, with ctx unless another
+// context is passed with options.WithContext.
+func KMSPublicKeyProvider(ctx context.Context, keyRef string) (signature.PublicKeyProvider, error) {
+	sv, err := kms.Get(ctx, keyRef, crypto.SHA256)
+	if err != nil {
+		return nil, fmt.Errorf("loading kms key: %w", err)
+	}
+	return &kmsPublicKeyProvider{ctx: ctx, sv: sv}, nil
+}
+
+// KMSKeysBytes returns the public key of the KMS key referenced by keyRef,
+// encoded with KeyToPem like the PublicBytes of GenerateKeyPair, so that KMS
+// and local keys can be handled the same way. PrivateBytes is always empty,
+// since the private key never leaves KMS.
+func KMSKeysBytes(ctx context.Context, keyRef string) (*KeysBytes, error) {
+	p, err := KMSPublicKeyProvider(ctx, keyRef)
+	if err != nil {
+		return nil, err
+	}
+	pubBytes, err := PublicKeyPemFromProvider(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	return &KeysBytes{PublicBytes: pubBytes}, nil
+}
+
+// PublicKeyPemFromProvider fetches the public key of p with ctx, and returns
+// it encoded with KeyToPem. Any signature.PublicKeyProvider is accepted, such
+// as the SignerVerifiers of the signature package or KMSPublicKeyProvider.
+func PublicKeyPemFromProvider(ctx context.Context, p signature.PublicKeyProvider) ([]byte, error) {
+	if p == nil {
+		return nil, errors.New("public key provider is nil")
+	}
+	pub, err := p.PublicKey(options.WithContext(ctx))
+	if err != nil {
+		return nil, fmt.Errorf("fetching public key: %w", err)
+	}
+	return KeyToPem(pub)
+}
@@ -0,0 +1,130 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"errors"
+	"testing"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+	"github.com/sigstore/sigstore/pkg/signature/kms"
+	"github.com/sigstore/sigstore/pkg/signature/options"
+	"github.com/stretchr/testify/require"
+)
+
+type contextKey struct{}
+
+// fakeKMS is a kms.SignerVerifier backed by a local key. It records the
+// context passed to PublicKey.
+type fakeKMS struct {
+	signature.SignerVerifier
+	ctx context.Context
+}
+
+func (f *fakeKMS) PublicKey(opts ...signature.PublicKeyOption) (crypto.PublicKey, error) {
+	f.ctx = context.Background()
+	for _, opt := range opts {
+		opt.ApplyContext(&f.ctx)
+	}
+	return f.SignerVerifier.PublicKey(opts...)
+}
+
+func (f *fakeKMS) CreateKey(context.Context, string) (crypto.PublicKey, error) {
+	return f.SignerVerifier.PublicKey()
+}
+
+func (f *fakeKMS) CryptoSigner(context.Context, func(error)) (crypto.Signer, crypto.SignerOpts, error) {
+	return nil, nil, nil
+}
+
+func (f *fakeKMS) SupportedAlgorithms() []string { return []string{"ecdsa-p256"} }
+
+func (f *fakeKMS) DefaultAlgorithm() string { return "ecdsa-p256" }
+
+func TestKMSPublicKeyProvider(t *testing.T) {
+	priv, err := GeneratePrivateKey()
+	require.NoError(t, err)
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	require.NoError(t, err)
+	fake := &fakeKMS{SignerVerifier: sv}
+	kms.AddProvider("fakekms://", func(context.Context, string, crypto.Hash, ...signature.RPCOption) (kms.SignerVerifier, error) {
+		return fake, nil
+	})
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "created")
+	p, err := KMSPublicKeyProvider(ctx, "fakekms://key")
+	require.NoError(t, err)
+	pub, err := p.PublicKey()
+	require.NoError(t, err)
+	require.True(t, EqualPublicKeys(priv.Public(), pub.(*ecdsa.PublicKey)))
+	require.Equal(t, "created", fake.ctx.Value(contextKey{}))
+
+	// A context passed by the caller takes precedence
+	callerCtx := context.WithValue(context.Background(), contextKey{}, "caller")
+	_, err = p.PublicKey(options.WithContext(callerCtx))
+	require.NoError(t, err)
+	require.Equal(t, "caller", fake.ctx.Value(contextKey{}))
+
+	// The PEM matches the one of a local key pair
+	keys, err := KMSKeysBytes(ctx, "fakekms://key")
+	require.NoError(t, err)
+	require.Empty(t, keys.PrivateBytes)
+	want, err := KeyToPem(priv.Public())
+	require.NoError(t, err)
+	require.Equal(t, want, keys.PublicBytes)
+
+	var perr *kms.ProviderNotFoundError
+	_, err = KMSPublicKeyProvider(ctx, "unknownkms://key")
+	require.ErrorAs(t, err, &perr)
+	_, err = KMSKeysBytes(ctx, "unknownkms://key")
+	require.ErrorAs(t, err, &perr)
+}
+
+// failingProvider is a signature.PublicKeyProvider that always fails.
+type failingProvider struct{}
+
+func (failingProvider) PublicKey(...signature.PublicKeyOption) (crypto.PublicKey, error) {
+	return nil, errors.New("provider unavailable")
+}
+
+func TestPublicKeyPemFromProvider(t *testing.T) {
+	priv, err := GeneratePrivateKey()
+	require.NoError(t, err)
+	sv, err := signature.LoadECDSASignerVerifier(priv, crypto.SHA256)
+	require.NoError(t, err)
+	fake := &fakeKMS{SignerVerifier: sv}
+
+	ctx := context.WithValue(context.Background(), contextKey{}, "caller")
+	pemBytes, err := PublicKeyPemFromProvider(ctx, fake)
+	require.NoError(t, err)
+	want, err := KeyToPem(priv.Public())
+	require.NoError(t, err)
+	require.Equal(t, want, pemBytes)
+	require.Equal(t, "caller", fake.ctx.Value(contextKey{}))
+
+	// SignerVerifiers are providers too
+	pemBytes, err = PublicKeyPemFromProvider(ctx, sv)
+	require.NoError(t, err)
+	require.Equal(t, want, pemBytes)
+
+	_, err = PublicKeyPemFromProvider(ctx, failingProvider{})
+	require.EqualError(t, err, "fetching public key: provider unavailable")
+	_, err = PublicKeyPemFromProvider(ctx, nil)
+	require.EqualError(t, err, "public key provider is nil")
+}
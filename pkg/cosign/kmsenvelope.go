@@ -0,0 +1,254 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// KMSEnvelopePrivateKeyPemType is the PEM type of private keys encrypted by
+// GenerateKeyPairKMSEnvelope with a data key wrapped by a KMS, rather than
+// with a passphrase.
+const KMSEnvelopePrivateKeyPemType = "KMS ENCRYPTED SIGSTORE PRIVATE KEY"
+
+// The private key is encrypted with a random data key, which is stored
+// wrapped by the KMS key next to the ciphertext.
+const kmsEnvelopeKDFName = "kms-envelope"
+
+// kmsEnvelope is the JSON body of a version 1 envelope, see envelope.go, of a
+// key encrypted with a KMS-wrapped data key.
+type kmsEnvelope struct {
+	KDF        kmsEnvelopeKDF `json:"kdf"`
+	Cipher     envelopeCipher `json:"cipher"`
+	Ciphertext []byte         `json:"ciphertext"`
+}
+
+type kmsEnvelopeKDF struct {
+	Name       string `json:"name"`
+	KeyRef     string `json:"key_ref"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// KeyWrapper wraps and unwraps data keys with a KMS key, e.g. with the
+// Encrypt and Decrypt operations of a cloud KMS.
+type KeyWrapper interface {
+	// WrapKey encrypts a data key with the KMS key.
+	WrapKey(ctx context.Context, dataKey []byte) ([]byte, error)
+	// UnwrapKey decrypts a data key wrapped by WrapKey.
+	UnwrapKey(ctx context.Context, wrapped []byte) ([]byte, error)
+}
+
+// KeyWrapperInit returns the KeyWrapper for a KMS key reference.
+type KeyWrapperInit func(ctx context.Context, keyRef string) (KeyWrapper, error)
+
+var keyWrappers = struct {
+	mu    sync.RWMutex
+	inits map[string]KeyWrapperInit
+}{inits: map[string]KeyWrapperInit{}}
+
+// RegisterKeyWrapper registers the KeyWrapper of the KMS key references that
+// start with prefix, e.g. "awskms://", for GenerateKeyPairKMSEnvelope and
+// LoadPrivateKeyKMSEnvelope. When prefixes overlap, e.g. "awskms://" and
+// "awskms://alias/", the longest matching one wins. cosign does not register
+// any KeyWrapper itself, as the signing KMS providers of the signature package
+// can't wrap keys.
+func RegisterKeyWrapper(prefix string, init KeyWrapperInit) error {
+	if prefix == "" || init == nil {
+		return errors.New("key wrapper prefix and init are required")
+	}
+	keyWrappers.mu.Lock()
+	defer keyWrappers.mu.Unlock()
+	if _, ok := keyWrappers.inits[prefix]; ok {
+		return fmt.Errorf("key wrapper for %q already registered", prefix)
+	}
+	keyWrappers.inits[prefix] = init
+	return nil
+}
+
+// keyWrapperFor returns the KeyWrapper for keyRef, registered with the longest
+// matching prefix.
+func keyWrapperFor(ctx context.Context, keyRef string) (KeyWrapper, error) {
+	keyWrappers.mu.RLock()
+	var match string
+	for prefix := range keyWrappers.inits {
+		if strings.HasPrefix(keyRef, prefix) && len(prefix) > len(match) {
+			match = prefix
+		}
+	}
+	init := keyWrappers.inits[match]
+	keyWrappers.mu.RUnlock()
+	if init == nil {
+		return nil, fmt.Errorf("no key wrapper registered for %q: cosign doesn't provide any, register one with RegisterKeyWrapper", keyRef)
+	}
+	return init(ctx, keyRef)
+}
+
+// GenerateKeyPairKMSEnvelope generates an ECDSA P-256 key pair and returns
+// the private key encrypted with a random data key, and the PEM-encoded
+// public key. The data key is wrapped by the KMS key referenced by kmsKeyRef,
+// whose KeyWrapper must be registered with RegisterKeyWrapper, and stored
+// with the private key, so decrypting it requires access to the KMS key, see
+// LoadPrivateKeyKMSEnvelope.
+func GenerateKeyPairKMSEnvelope(ctx context.Context, kmsKeyRef string) (*KeysBytes, error) {
+	wrapper, err := keyWrapperFor(ctx, kmsKeyRef)
+	if err != nil {
+		return nil, err
+	}
+	priv, err := GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	x509Encoded, err := marshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMarshalPrivateKey, err)
+	}
+	defer clear(x509Encoded)
+	body, err := encryptWithDataKey(ctx, x509Encoded, wrapper, kmsKeyRef)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncryptPrivateKey, err)
+	}
+	pubBytes, err := KeyToPem(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	keys := &KeysBytes{
+		PrivateBytes: pem.EncodeToMemory(&pem.Block{
+			Type:  KMSEnvelopePrivateKeyPemType,
+			Bytes: wrapEnvelope(envelopeVersion1, body),
+		}),
+		PublicBytes: pubBytes,
+		GeneratedAt: generatedNow(),
+		Algorithm:   ECDSAP256Algorithm,
+	}
+	// The self-test unwraps the data key with the KMS a second time
+	if err := selfTestKeyPairWith(keys, func() (signature.SignerVerifier, error) {
+		return LoadPrivateKeyKMSEnvelope(ctx, keys.PrivateBytes)
+	}); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// LoadPrivateKeyKMSEnvelope decrypts a private key generated by
+// GenerateKeyPairKMSEnvelope, unwrapping its data key with the KMS key it
+// references, and returns a SignerVerifier as with LoadPrivateKey. It returns
+// an error wrapping ErrDecryptFailed if the data key can't be unwrapped.
+func LoadPrivateKeyKMSEnvelope(ctx context.Context, key []byte) (signature.SignerVerifier, error) {
+	p, rest, err := decodePemSafely(key)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrInvalidPemBlock
+	}
+	if err := checkTrailingData(rest); err != nil {
+		return nil, err
+	}
+	if p.Type != KMSEnvelopePrivateKeyPemType {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPemType, p.Type)
+	}
+	version, body, err := unwrapEnvelope(p.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if version != envelopeVersion1 {
+		return nil, fmt.Errorf("unsupported envelope version %d", version)
+	}
+	x509Encoded, err := decryptWithDataKey(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := parsePKCS8PrivateKey(x509Encoded)
+	if err != nil {
+		return nil, err
+	}
+	return loadSignerVerifier(pk, defaultHashForPrivateKey(pk), RSAPKCS1v15Padding)
+}
+
+func encryptWithDataKey(ctx context.Context, plaintext []byte, wrapper KeyWrapper, keyRef string) ([]byte, error) {
+	var dataKey [secretboxKeySize]byte
+	defer clear(dataKey[:])
+	if _, err := io.ReadFull(rand.Reader, dataKey[:]); err != nil {
+		return nil, err
+	}
+	wrapped, err := wrapper.WrapKey(ctx, dataKey[:])
+	if err != nil {
+		return nil, fmt.Errorf("wrapping data key: %w", err)
+	}
+	env := kmsEnvelope{
+		KDF: kmsEnvelopeKDF{Name: kmsEnvelopeKDFName, KeyRef: keyRef, WrappedKey: wrapped},
+		Cipher: envelopeCipher{
+			Name:  nameSecretBox,
+			Nonce: make([]byte, secretboxNonceSize),
+		},
+	}
+	if _, err := io.ReadFull(rand.Reader, env.Cipher.Nonce); err != nil {
+		return nil, err
+	}
+	var nonce [secretboxNonceSize]byte
+	copy(nonce[:], env.Cipher.Nonce)
+	env.Ciphertext = secretbox.Seal(nil, plaintext, &nonce, &dataKey)
+	return json.Marshal(env)
+}
+
+func decryptWithDataKey(ctx context.Context, data []byte) ([]byte, error) {
+	var env kmsEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if env.KDF.Name != kmsEnvelopeKDFName {
+		return nil, fmt.Errorf("unsupported kdf: %q", env.KDF.Name)
+	}
+	if env.Cipher.Name != nameSecretBox {
+		return nil, fmt.Errorf("unknown cipher name %q", env.Cipher.Name)
+	}
+	if len(env.Cipher.Nonce) != secretboxNonceSize {
+		return nil, errors.New("incorrect nonce size")
+	}
+	wrapper, err := keyWrapperFor(ctx, env.KDF.KeyRef)
+	if err != nil {
+		return nil, err
+	}
+	dataKey, err := wrapper.UnwrapKey(ctx, env.KDF.WrappedKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: unwrapping data key: %w", ErrDecryptFailed, err)
+	}
+	defer clear(dataKey)
+	if len(dataKey) != secretboxKeySize {
+		return nil, fmt.Errorf("%w: unwrapped data key is %d bytes, want %d", ErrDecryptFailed, len(dataKey), secretboxKeySize)
+	}
+	var key [secretboxKeySize]byte
+	defer clear(key[:])
+	copy(key[:], dataKey)
+	var nonce [secretboxNonceSize]byte
+	copy(nonce[:], env.Cipher.Nonce)
+	plaintext, ok := secretbox.Open(nil, env.Ciphertext, &nonce, &key)
+	if !ok {
+		return nil, fmt.Errorf("%w: decryption failed", ErrDecryptFailed)
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,154 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// fakeWrappingKMS wraps data keys with a secretbox key per KMS key reference, and
+// counts the unwrap calls.
+type fakeWrappingKMS struct {
+	keys    map[string]*[secretboxKeySize]byte
+	unwraps int
+	denied  bool
+}
+
+type fakeKeyWrapper struct {
+	kms *fakeWrappingKMS
+	key *[secretboxKeySize]byte
+}
+
+func (w fakeKeyWrapper) WrapKey(_ context.Context, dataKey []byte) ([]byte, error) {
+	var nonce [secretboxNonceSize]byte
+	if _, err := rand.Read(nonce[:]); err != nil {
+		return nil, err
+	}
+	return secretbox.Seal(nonce[:], dataKey, &nonce, w.key), nil
+}
+
+func (w fakeKeyWrapper) UnwrapKey(_ context.Context, wrapped []byte) ([]byte, error) {
+	w.kms.unwraps++
+	if w.kms.denied {
+		return nil, errors.New("permission denied")
+	}
+	if len(wrapped) < secretboxNonceSize {
+		return nil, errors.New("invalid ciphertext")
+	}
+	var nonce [secretboxNonceSize]byte
+	copy(nonce[:], wrapped)
+	dataKey, ok := secretbox.Open(nil, wrapped[secretboxNonceSize:], &nonce, w.key)
+	if !ok {
+		return nil, errors.New("invalid ciphertext")
+	}
+	return dataKey, nil
+}
+
+func registerFakeKMS(t *testing.T) *fakeWrappingKMS {
+	t.Helper()
+	kms := &fakeWrappingKMS{keys: map[string]*[secretboxKeySize]byte{}}
+	require.NoError(t, RegisterKeyWrapper("fakekms://", func(_ context.Context, keyRef string) (KeyWrapper, error) {
+		key, ok := kms.keys[keyRef]
+		if !ok {
+			key = new([secretboxKeySize]byte)
+			if _, err := rand.Read(key[:]); err != nil {
+				return nil, err
+			}
+			kms.keys[keyRef] = key
+		}
+		return fakeKeyWrapper{kms: kms, key: key}, nil
+	}))
+	t.Cleanup(func() {
+		keyWrappers.mu.Lock()
+		defer keyWrappers.mu.Unlock()
+		delete(keyWrappers.inits, "fakekms://")
+	})
+	return kms
+}
+
+func TestKMSEnvelope(t *testing.T) {
+	kms := registerFakeKMS(t)
+	ctx := context.Background()
+
+	keys, err := GenerateKeyPairKMSEnvelope(ctx, "fakekms://key1")
+	require.NoError(t, err)
+	require.Equal(t, KMSEnvelopePrivateKeyPemType, mustDecodePem(t, string(keys.PrivateBytes)).Type)
+	// The self-test unwraps the data key once
+	require.Equal(t, 1, kms.unwraps)
+
+	sv, err := LoadPrivateKeyKMSEnvelope(ctx, keys.PrivateBytes)
+	require.NoError(t, err)
+	require.Equal(t, 2, kms.unwraps)
+	pub, err := sv.PublicKey()
+	require.NoError(t, err)
+	require.Equal(t, mustLoadPublicKey(t, keys.PublicBytes), pub)
+	sig, err := sv.SignMessage(bytes.NewReader([]byte("payload")))
+	require.NoError(t, err)
+	require.NoError(t, VerifyBytes(pub, []byte("payload"), sig))
+
+	// Decryption requires access to the KMS key
+	kms.denied = true
+	_, err = LoadPrivateKeyKMSEnvelope(ctx, keys.PrivateBytes)
+	require.ErrorIs(t, err, ErrDecryptFailed)
+	require.ErrorContains(t, err, "permission denied")
+	_, err = GenerateKeyPairKMSEnvelope(ctx, "fakekms://key1")
+	require.ErrorIs(t, err, ErrSelfTest)
+	kms.denied = false
+	kms.keys["fakekms://key1"] = new([secretboxKeySize]byte)
+	_, err = LoadPrivateKeyKMSEnvelope(ctx, keys.PrivateBytes)
+	require.ErrorIs(t, err, ErrDecryptFailed)
+
+	// The key can't be loaded as a passphrase encrypted key, and vice versa
+	_, err = LoadPrivateKey(keys.PrivateBytes, nil)
+	require.ErrorIs(t, err, ErrUnsupportedPemType)
+	pwKeys, err := GenerateKeyPair(pass("hello"))
+	require.NoError(t, err)
+	_, err = LoadPrivateKeyKMSEnvelope(ctx, pwKeys.PrivateBytes)
+	require.ErrorIs(t, err, ErrUnsupportedPemType)
+
+	_, err = GenerateKeyPairKMSEnvelope(ctx, "otherkms://key")
+	require.EqualError(t, err, `no key wrapper registered for "otherkms://key": cosign doesn't provide any, register one with RegisterKeyWrapper`)
+	require.ErrorContains(t, RegisterKeyWrapper("fakekms://", nil), "required")
+	require.ErrorContains(t, RegisterKeyWrapper("fakekms://", func(context.Context, string) (KeyWrapper, error) { return nil, nil }), "already registered")
+}
+
+func TestKeyWrapperLongestPrefix(t *testing.T) {
+	registerFakeKMS(t)
+	aliases := &fakeWrappingKMS{keys: map[string]*[secretboxKeySize]byte{}}
+	require.NoError(t, RegisterKeyWrapper("fakekms://alias/", func(context.Context, string) (KeyWrapper, error) {
+		return fakeKeyWrapper{kms: aliases, key: new([secretboxKeySize]byte)}, nil
+	}))
+	t.Cleanup(func() {
+		keyWrappers.mu.Lock()
+		defer keyWrappers.mu.Unlock()
+		delete(keyWrappers.inits, "fakekms://alias/")
+	})
+
+	ctx := context.Background()
+	for i := 0; i < 10; i++ {
+		_, err := GenerateKeyPairKMSEnvelope(ctx, "fakekms://alias/key")
+		require.NoError(t, err)
+	}
+	// Every self-test went through the wrapper of the longest prefix
+	require.Equal(t, 10, aliases.unwraps)
+}
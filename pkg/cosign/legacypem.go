@@ -0,0 +1,88 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+)
+
+// isLegacyEncryptedPem reports whether key is a PEM block encrypted with the
+// legacy OpenSSL scheme of RFC 1423, marked by its Proc-Type and DEK-Info
+// headers.
+func isLegacyEncryptedPem(key []byte) bool {
+	p, _, err := decodePemSafely(key)
+	if err != nil || p == nil {
+		return false
+	}
+	return isLegacyEncryptedBlock(p)
+}
+
+func isLegacyEncryptedBlock(p *pem.Block) bool {
+	_, ok := p.Headers["DEK-Info"]
+	return ok && p.Headers["Proc-Type"] == "4,ENCRYPTED"
+}
+
+// decryptLegacyPrivateKey decrypts an "EC PRIVATE KEY", "RSA PRIVATE KEY" or
+// "PRIVATE KEY" block encrypted with the legacy OpenSSL scheme, see
+// LoadKeyOpts.AllowLegacyEncryptedPEM.
+//
+// The scheme derives the key with a single round of MD5, and isn't
+// authenticated: a wrong passphrase is only detected by its padding, so it
+// may instead yield garbage that fails to parse.
+func decryptLegacyPrivateKey(key, pass []byte) (crypto.PrivateKey, error) {
+	p, rest, err := decodePemSafely(key)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrInvalidPemBlock
+	}
+	if err := checkTrailingData(rest); err != nil {
+		return nil, err
+	}
+	if !isLegacyEncryptedBlock(p) {
+		return nil, errors.New("not a legacy encrypted pem block")
+	}
+	//nolint:staticcheck // Deprecated for being insecure, which callers opt into
+	der, err := x509.DecryptPEMBlock(p, pass)
+	if err != nil {
+		return nil, decryptError(err, pass)
+	}
+	defer clear(der)
+
+	var pk crypto.PrivateKey
+	switch p.Type {
+	case ECPrivateKeyPemType:
+		pk, err = x509.ParseECPrivateKey(der)
+	case RSAPrivateKeyPemType:
+		pk, err = x509.ParsePKCS1PrivateKey(der)
+	case PrivateKeyPemType:
+		pk, err = parsePKCS8PrivateKeyDER(der)
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPemType, p.Type)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing private key: %w", err)
+	}
+	if err := validatePrivateKey(pk); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
@@ -0,0 +1,74 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+)
+
+// StaticPassFunc returns a PassFunc that always returns a copy of pw. The
+// confirm argument is ignored.
+func StaticPassFunc(pw []byte) PassFunc {
+	return func(_ bool) ([]byte, error) {
+		return append([]byte{}, pw...), nil
+	}
+}
+
+// EnvPassFunc returns a PassFunc that reads the password from the environment
+// variable varName. It returns an error if the variable is unset, but an empty
+// value that is explicitly set is returned as is. The confirm argument is
+// ignored.
+func EnvPassFunc(varName string) PassFunc {
+	return func(_ bool) ([]byte, error) {
+		pw, ok := os.LookupEnv(varName) //nolint:forbidigo
+		if !ok {
+			return nil, fmt.Errorf("environment variable %s is not set", varName)
+		}
+		return []byte(pw), nil
+	}
+}
+
+// FilePassFunc returns a PassFunc that reads the password from the file at
+// path, with a single trailing newline removed. Other whitespace is kept, as
+// it may be part of the password. The file must not be accessible by group or
+// other users, i.e. its permissions must be 0600 or stricter, except on
+// Windows. The confirm argument is ignored.
+func FilePassFunc(path string) PassFunc {
+	return func(_ bool) ([]byte, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, fmt.Errorf("opening password file: %w", err)
+		}
+		defer f.Close()
+		fi, err := f.Stat()
+		if err != nil {
+			return nil, fmt.Errorf("reading password file: %w", err)
+		}
+		// Windows does not report Unix permissions
+		if perm := fi.Mode().Perm(); runtime.GOOS != "windows" && perm&0o077 != 0 {
+			return nil, fmt.Errorf("password file %s has permissions %#o, must be 0600 or stricter", path, perm)
+		}
+		pw, err := io.ReadAll(f)
+		if err != nil {
+			return nil, fmt.Errorf("reading password file: %w", err)
+		}
+		return bytes.TrimSuffix(pw, []byte("\n")), nil
+	}
+}
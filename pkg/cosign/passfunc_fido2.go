@@ -0,0 +1,64 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Fido2SaltSize is the size of the salts of the FIDO2 hmac-secret extension.
+const Fido2SaltSize = 32
+
+// Fido2Token derives secrets with the hmac-secret extension of a FIDO2
+// security key.
+type Fido2Token interface {
+	// HMACSecret returns the hmac-secret output of the credential for salt.
+	// Each call requires user presence, i.e. a touch of the token.
+	HMACSecret(credentialID, salt []byte) ([]byte, error)
+}
+
+// Fido2PassFunc returns a PassFunc that uses the hmac-secret of the FIDO2
+// credential credentialID for salt, as derived by token, as the encryption
+// secret instead of a typed passphrase. The secret is the same for every call
+// with the same token, credential and salt, so confirm is ignored: a second
+// touch could only return the same secret.
+//
+// Fido2Token is only an extension point. cosign does not bundle a libfido2
+// binding or any implementation of it, so token is implemented by the caller,
+// e.g. on top of the binding of their platform.
+func Fido2PassFunc(token Fido2Token, credentialID, salt []byte) PassFunc {
+	return func(bool) ([]byte, error) {
+		if len(credentialID) == 0 {
+			return nil, errors.New("fido2 credential id is empty")
+		}
+		if len(salt) != Fido2SaltSize {
+			return nil, fmt.Errorf("fido2 salt is %d bytes, want %d", len(salt), Fido2SaltSize)
+		}
+		return fido2Secret(token, credentialID, salt)
+	}
+}
+
+func fido2Secret(token Fido2Token, credentialID, salt []byte) ([]byte, error) {
+	secret, err := token.HMACSecret(credentialID, salt)
+	if err != nil {
+		return nil, fmt.Errorf("deriving secret from fido2 token: %w", err)
+	}
+	if len(secret) == 0 {
+		return nil, errors.New("fido2 token returned an empty secret")
+	}
+	return secret, nil
+}
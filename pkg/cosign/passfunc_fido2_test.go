@@ -0,0 +1,74 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mockFido2Token derives hmac-secrets like a FIDO2 token holding a single
+// credential, and counts the touches.
+type mockFido2Token struct {
+	credentialID []byte
+	key          []byte
+	touches      int
+}
+
+func (m *mockFido2Token) HMACSecret(credentialID, salt []byte) ([]byte, error) {
+	m.touches++
+	if !bytes.Equal(credentialID, m.credentialID) {
+		return nil, errors.New("no credentials")
+	}
+	mac := hmac.New(sha256.New, m.key)
+	mac.Write(salt)
+	return mac.Sum(nil), nil
+}
+
+func TestFido2PassFunc(t *testing.T) {
+	token := &mockFido2Token{credentialID: []byte("credential"), key: []byte("token key")}
+	salt := bytes.Repeat([]byte{1}, Fido2SaltSize)
+	pf := Fido2PassFunc(token, token.credentialID, salt)
+
+	// Generating a key and loading it require one touch each
+	keys, err := GenerateKeyPair(pf)
+	require.NoError(t, err)
+	require.Equal(t, 1, token.touches)
+	secret, err := pf(false)
+	require.NoError(t, err)
+	require.Equal(t, 2, token.touches)
+	_, err = LoadPrivateKey(keys.PrivateBytes, secret)
+	require.NoError(t, err)
+
+	// Another salt derives another secret
+	other, err := Fido2PassFunc(token, token.credentialID, bytes.Repeat([]byte{2}, Fido2SaltSize))(false)
+	require.NoError(t, err)
+	require.NotEqual(t, secret, other)
+	_, err = LoadPrivateKey(keys.PrivateBytes, other)
+	require.Error(t, err)
+
+	_, err = Fido2PassFunc(token, []byte("unknown"), salt)(false)
+	require.ErrorContains(t, err, "deriving secret from fido2 token: no credentials")
+	_, err = Fido2PassFunc(token, nil, salt)(false)
+	require.EqualError(t, err, "fido2 credential id is empty")
+	_, err = Fido2PassFunc(token, token.credentialID, salt[1:])(false)
+	require.EqualError(t, err, "fido2 salt is 31 bytes, want 32")
+}
@@ -0,0 +1,98 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrKeyringNotFound is returned by a Keyring when it holds no secret for
+	// the service and account.
+	ErrKeyringNotFound = errors.New("secret not found in keyring")
+	// ErrKeyringUnavailable is returned when there is no usable OS keyring,
+	// e.g. on headless systems without a secret service, or when cosign was
+	// built without the keyring tag.
+	ErrKeyringUnavailable = errors.New("os keyring is unavailable")
+)
+
+// Keyring stores secrets by service and account, like the OS keyrings used by
+// KeyringPassFunc.
+type Keyring interface {
+	// Get returns the secret stored for service and account, or an error
+	// wrapping ErrKeyringNotFound if there is none.
+	Get(service, account string) (string, error)
+	// Set stores secret for service and account, replacing any previous one.
+	Set(service, account, secret string) error
+}
+
+// KeyringPassFunc returns a PassFunc that keeps the passphrase in the OS
+// keyring (macOS Keychain, Windows Credential Manager or the freedesktop.org
+// Secret Service) under service and account. The passphrase is prompted for
+// on the terminal when confirm is set or when the keyring holds none yet;
+// otherwise the stored passphrase is returned without prompting.
+//
+// A prompted passphrase is only stored by commit, which callers must call
+// once the passphrase is known to be right: after generating the key it
+// encrypts, or after loading the key without ErrDecryptFailed. A mistyped
+// passphrase would otherwise be returned forever without prompting again.
+// commit does nothing if nothing was prompted for.
+//
+// OS keyring support requires cosign to be built with the keyring tag.
+// Without it, or without a usable keyring, the PassFunc returns an error
+// wrapping ErrKeyringUnavailable.
+func KeyringPassFunc(service, account string) (pf PassFunc, commit func() error) {
+	return KeyringPassFuncWith(osKeyring, service, account, GetPassFromTerm)
+}
+
+// KeyringPassFuncWith is KeyringPassFunc with the given keyring, and prompt
+// as the source of the passphrase to store.
+func KeyringPassFuncWith(kr Keyring, service, account string, prompt PassFunc) (pf PassFunc, commit func() error) {
+	var prompted []byte
+	pending := false
+	pf = func(confirm bool) ([]byte, error) {
+		if !confirm {
+			pw, err := kr.Get(service, account)
+			if err == nil {
+				return []byte(pw), nil
+			}
+			if !errors.Is(err, ErrKeyringNotFound) {
+				return nil, fmt.Errorf("reading passphrase from keyring: %w", err)
+			}
+		}
+		pw, err := prompt(confirm)
+		if err != nil {
+			return nil, err
+		}
+		// Callers may clear pw once they are done with it.
+		prompted, pending = bytes.Clone(pw), true
+		return pw, nil
+	}
+	commit = func() error {
+		if !pending {
+			return nil
+		}
+		if err := kr.Set(service, account, string(prompted)); err != nil {
+			return fmt.Errorf("storing passphrase in keyring: %w", err)
+		}
+		clear(prompted)
+		prompted, pending = nil, false
+		return nil
+	}
+	return pf, commit
+}
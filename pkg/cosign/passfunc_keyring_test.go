@@ -0,0 +1,90 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+// mapKeyring is an in-memory Keyring.
+type mapKeyring map[string]string
+
+func (m mapKeyring) Get(service, account string) (string, error) {
+	secret, ok := m[service+"/"+account]
+	if !ok {
+		return "", ErrKeyringNotFound
+	}
+	return secret, nil
+}
+
+func (m mapKeyring) Set(service, account, secret string) error {
+	m[service+"/"+account] = secret
+	return nil
+}
+
+func TestKeyringPassFunc(t *testing.T) {
+	kr := mapKeyring{}
+	prompts := 0
+	prompt := func(bool) ([]byte, error) {
+		prompts++
+		return []byte("hello"), nil
+	}
+	pf, commit := KeyringPassFuncWith(kr, "cosign", "cosign.key", prompt)
+
+	// Generating a key prompts, and the passphrase is stored on commit
+	keys, err := GenerateKeyPair(pf)
+	require.NoError(t, err)
+	require.Equal(t, 1, prompts)
+	require.Empty(t, kr)
+	require.NoError(t, commit())
+	require.Equal(t, mapKeyring{"cosign/cosign.key": "hello"}, kr)
+
+	// Loading it doesn't prompt again
+	pw, err := pf(false)
+	require.NoError(t, err)
+	_, err = LoadPrivateKey(keys.PrivateBytes, pw)
+	require.NoError(t, err)
+	require.Equal(t, 1, prompts)
+	require.NoError(t, commit())
+	require.Len(t, kr, 1)
+
+	// A missing passphrase is prompted for on load as well
+	pf, commit = KeyringPassFuncWith(kr, "cosign", "other.key", prompt)
+	pw, err = pf(false)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), pw)
+	require.Equal(t, 2, prompts)
+	require.NoError(t, commit())
+	require.Len(t, kr, 2)
+
+	// A mistyped passphrase is not stored unless committed
+	pf, _ = KeyringPassFuncWith(kr, "cosign", "typo.key", func(bool) ([]byte, error) { return []byte("helo"), nil })
+	pw, err = pf(false)
+	require.NoError(t, err)
+	_, err = LoadPrivateKey(keys.PrivateBytes, pw)
+	require.ErrorIs(t, err, ErrDecryptFailed)
+	require.Len(t, kr, 2)
+
+	cancelled := errors.New("cancelled")
+	pf, commit = KeyringPassFuncWith(kr, "cosign", "new.key", func(bool) ([]byte, error) { return nil, cancelled })
+	_, err = pf(true)
+	require.ErrorIs(t, err, cancelled)
+	require.NoError(t, commit())
+	require.Len(t, kr, 2)
+}
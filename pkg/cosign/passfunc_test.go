@@ -0,0 +1,101 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStaticPassFunc(t *testing.T) {
+	pw := []byte("hello")
+	pf := StaticPassFunc(pw)
+
+	for _, confirm := range []bool{true, false} {
+		got, err := pf(confirm)
+		require.NoError(t, err)
+		require.Equal(t, []byte("hello"), got)
+	}
+
+	// Callers modifying the returned password must not affect later calls.
+	got, err := pf(false)
+	require.NoError(t, err)
+	got[0] = 'x'
+	got, err = pf(false)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), got)
+}
+
+func TestEnvPassFunc(t *testing.T) {
+	t.Setenv("COSIGN_TEST_PASSWORD", "hello")
+	got, err := EnvPassFunc("COSIGN_TEST_PASSWORD")(true)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), got)
+
+	t.Setenv("COSIGN_TEST_EMPTY_PASSWORD", "")
+	got, err = EnvPassFunc("COSIGN_TEST_EMPTY_PASSWORD")(false)
+	require.NoError(t, err)
+	require.Empty(t, got)
+
+	_, err = EnvPassFunc("COSIGN_TEST_UNSET_PASSWORD")(false)
+	require.EqualError(t, err, "environment variable COSIGN_TEST_UNSET_PASSWORD is not set")
+
+	// The PassFunc can be used to generate and load keys
+	keys, err := GenerateKeyPair(EnvPassFunc("COSIGN_TEST_PASSWORD"))
+	require.NoError(t, err)
+	_, err = LoadPrivateKey(keys.PrivateBytes, []byte("hello"))
+	require.NoError(t, err)
+}
+
+func TestFilePassFunc(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string, perm os.FileMode) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), perm))
+		require.NoError(t, os.Chmod(path, perm))
+		return path
+	}
+
+	for content, want := range map[string]string{
+		"hello":          "hello",
+		"hello\n":        "hello",
+		"hello\n\n":      "hello\n",
+		" hello world  ": " hello world  ",
+		"":               "",
+	} {
+		got, err := FilePassFunc(write("password", content, 0o600))(true)
+		require.NoError(t, err)
+		require.Equal(t, []byte(want), got)
+	}
+
+	got, err := FilePassFunc(write("readonly", "hello\n", 0o400))(false)
+	require.NoError(t, err)
+	require.Equal(t, []byte("hello"), got)
+
+	if runtime.GOOS != "windows" {
+		_, err = FilePassFunc(write("groupreadable", "hello", 0o640))(false)
+		require.ErrorContains(t, err, "has permissions 0640, must be 0600 or stricter")
+		_, err = FilePassFunc(write("worldreadable", "hello", 0o604))(false)
+		require.ErrorContains(t, err, "has permissions 0604")
+	}
+
+	_, err = FilePassFunc(filepath.Join(dir, "missing"))(false)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
@@ -0,0 +1,64 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto/x509/pkix"
+	"encoding/asn1"
+	"errors"
+
+	"go.step.sm/crypto/pemutil"
+)
+
+// encryptedPrivateKeyInfo is the EncryptedPrivateKeyInfo structure of RFC 5208.
+type encryptedPrivateKeyInfo struct {
+	Algorithm     pkix.AlgorithmIdentifier
+	EncryptedData []byte
+}
+
+// decryptPKCS8PrivateKey decrypts a DER-encoded PKCS #8 EncryptedPrivateKeyInfo,
+// as produced by `openssl pkcs8 -topk8`, and returns the PKCS #8 encoded
+// private key. Only PBES2 with PBKDF2 is supported, see
+// pemutil.DecryptPKCS8PrivateKey.
+func decryptPKCS8PrivateKey(der []byte, pass []byte) (_ []byte, err error) {
+	if !isEncryptedPKCS8(der) {
+		return nil, errors.New("parsing encrypted private key: not a PKCS #8 EncryptedPrivateKeyInfo")
+	}
+	// pemutil decrypts in place, and panics on IVs and ciphertexts that are
+	// not a whole number of blocks.
+	defer recoverParsePanic(&err)
+	return pemutil.DecryptPKCS8PrivateKey(bytes.Clone(der), pass)
+}
+
+// isEncryptedPKCS8 reports whether der is a PKCS #8 EncryptedPrivateKeyInfo,
+// rather than an unencrypted PrivateKeyInfo, which starts with a version.
+func isEncryptedPKCS8(der []byte) bool {
+	var info encryptedPrivateKeyInfo
+	return unmarshalDER(der, &info) == nil
+}
+
+// unmarshalDER parses a DER-encoded value and rejects trailing data.
+func unmarshalDER(der []byte, v interface{}) error {
+	rest, err := asn1.Unmarshal(der, v)
+	if err != nil {
+		return err
+	}
+	if len(rest) != 0 {
+		return errors.New("trailing data")
+	}
+	return nil
+}
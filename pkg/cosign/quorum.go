@@ -0,0 +1,81 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto"
+	"errors"
+	"fmt"
+)
+
+// ErrQuorumNotMet is returned by VerifyQuorum when fewer keys than the
+// threshold have a valid signature.
+var ErrQuorumNotMet = errors.New("signature quorum not met")
+
+// VerifyQuorum verifies that at least threshold distinct keys in keys each
+// have a valid signature over payload in sigs. A signature is valid for a key
+// if it verifies with VerifyBytes, as made by SignBytes or LoadPrivateKey.
+// Keys listed more than once count once, and each
+// signature is counted for at most one key. At most len(keys)*len(sigs)
+// signatures are checked.
+//
+// If the threshold is not met, the returned error wraps ErrQuorumNotMet and
+// the reason each key without a valid signature was rejected.
+func VerifyQuorum(keys []crypto.PublicKey, payload []byte, sigs [][]byte, threshold int) error {
+	if threshold < 1 {
+		return fmt.Errorf("invalid signature threshold: %d", threshold)
+	}
+	// The indices in keys of the first occurrence of each key
+	var distinct []int
+	seen := map[string]bool{}
+	for i, pub := range keys {
+		der, err := marshalPKIXPublicKey(pub)
+		if err != nil {
+			return fmt.Errorf("key %d: marshaling public key: %w", i, err)
+		}
+		if !seen[string(der)] {
+			seen[string(der)] = true
+			distinct = append(distinct, i)
+		}
+	}
+	if threshold > len(distinct) {
+		return fmt.Errorf("signature threshold %d exceeds the %d distinct keys", threshold, len(distinct))
+	}
+
+	used := make([]bool, len(sigs))
+	verified := 0
+	var errs []error
+	for _, i := range distinct {
+		found := false
+		for j, sig := range sigs {
+			if used[j] {
+				continue
+			}
+			if VerifyBytes(keys[i], payload, sig) == nil {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			errs = append(errs, fmt.Errorf("key %d: no valid signature", i))
+			continue
+		}
+		if verified++; verified == threshold {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %d of %d required keys verified: %w", ErrQuorumNotMet, verified, threshold, errors.Join(errs...))
+}
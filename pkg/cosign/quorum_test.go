@@ -0,0 +1,68 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyQuorum(t *testing.T) {
+	payload := []byte("payload")
+	var keys []crypto.PublicKey
+	var sigs [][]byte
+	for _, alg := range []string{ECDSAP256Algorithm, ECDSAP384Algorithm, ED25519Algorithm} {
+		kb, err := GenerateKeyPairWithAlgorithm(pass("hello"), alg)
+		require.NoError(t, err)
+		keys = append(keys, mustLoadPublicKey(t, kb.PublicBytes))
+		sig, err := SignBytes(kb.PrivateBytes, []byte("hello"), payload)
+		require.NoError(t, err)
+		sigs = append(sigs, sig)
+	}
+	rsaKeys, err := GenerateRSAKeyPair(pass("hello"), 2048)
+	require.NoError(t, err)
+	rsaSig, err := SignBytes(rsaKeys.PrivateBytes, []byte("hello"), payload)
+	require.NoError(t, err)
+	keys = append(keys, mustLoadPublicKey(t, rsaKeys.PublicBytes))
+
+	// Thresholds up to the number of signing keys are met, in any order
+	for threshold := 1; threshold <= 3; threshold++ {
+		require.NoError(t, VerifyQuorum(keys, payload, sigs, threshold))
+		require.NoError(t, VerifyQuorum(keys, payload, [][]byte{sigs[2], sigs[1], sigs[0]}, threshold))
+	}
+	require.NoError(t, VerifyQuorum(keys, payload, append(sigs, rsaSig), 4))
+
+	err = VerifyQuorum(keys, payload, sigs, 4)
+	require.ErrorIs(t, err, ErrQuorumNotMet)
+	require.EqualError(t, err, "signature quorum not met: 3 of 4 required keys verified: key 3: no valid signature")
+
+	// A signature counts once, and so does a key listed twice
+	err = VerifyQuorum(keys, payload, [][]byte{sigs[0], sigs[0]}, 2)
+	require.ErrorIs(t, err, ErrQuorumNotMet)
+	err = VerifyQuorum([]crypto.PublicKey{keys[0], keys[0], keys[1]}, payload, [][]byte{sigs[0], sigs[0]}, 2)
+	require.ErrorIs(t, err, ErrQuorumNotMet)
+	require.EqualError(t, VerifyQuorum([]crypto.PublicKey{keys[0], keys[0]}, payload, sigs, 2), "signature threshold 2 exceeds the 1 distinct keys")
+
+	// Signatures over another payload don't count
+	err = VerifyQuorum(keys, []byte("other"), sigs, 1)
+	require.ErrorIs(t, err, ErrQuorumNotMet)
+	require.ErrorContains(t, err, "key 0: no valid signature\nkey 1: no valid signature\nkey 2: no valid signature\nkey 3: no valid signature")
+
+	require.EqualError(t, VerifyQuorum(keys, payload, sigs, 0), "invalid signature threshold: 0")
+	require.ErrorContains(t, VerifyQuorum([]crypto.PublicKey{nil}, payload, sigs, 1), "key 0: marshaling public key")
+}
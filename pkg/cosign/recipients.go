@@ -0,0 +1,310 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+	"golang.org/x/crypto/hkdf"
+	"golang.org/x/crypto/nacl/secretbox"
+)
+
+// RecipientsPrivateKeyPemType is the PEM type of private keys encrypted to a
+// set of X25519 recipients by GenerateKeyPairToRecipients, rather than with a
+// passphrase.
+const RecipientsPrivateKeyPemType = "ENCRYPTED SIGSTORE PRIVATE KEY FOR RECIPIENTS"
+
+// The private key is encrypted, as with age, with a random file key. The file
+// key is then wrapped for each recipient with a key derived by HKDF-SHA256
+// from an X25519 exchange with a per-recipient ephemeral key.
+const (
+	recipientsKDFName  = "x25519-hkdf-sha256"
+	recipientsHKDFInfo = "sigstore x25519 recipient"
+)
+
+// recipientsEnvelope is the JSON body of a version 1 envelope, see
+// envelope.go, of a key encrypted to recipients.
+type recipientsEnvelope struct {
+	KDF        recipientsKDF  `json:"kdf"`
+	Cipher     envelopeCipher `json:"cipher"`
+	Ciphertext []byte         `json:"ciphertext"`
+}
+
+type recipientsKDF struct {
+	Name       string            `json:"name"`
+	Recipients []recipientStanza `json:"recipients"`
+}
+
+// recipientStanza holds the file key, wrapped for one recipient.
+type recipientStanza struct {
+	Ephemeral  []byte `json:"ephemeral"`
+	Nonce      []byte `json:"nonce"`
+	WrappedKey []byte `json:"wrapped_key"`
+}
+
+// GenerateKeyPairToRecipients generates an ECDSA P-256 key pair and returns
+// the private key encrypted to recipients, which must be X25519
+// *ecdh.PublicKey values, and the PEM-encoded public key. Any one of the
+// matching X25519 private keys decrypts the private key, see
+// LoadPrivateKeyForRecipient.
+func GenerateKeyPairToRecipients(recipients []crypto.PublicKey) (*KeysBytes, error) {
+	if len(recipients) == 0 {
+		return nil, errors.New("no recipients")
+	}
+	keys := make([]*ecdh.PublicKey, 0, len(recipients))
+	for i, r := range recipients {
+		k, ok := r.(*ecdh.PublicKey)
+		if !ok || k.Curve() != ecdh.X25519() {
+			return nil, fmt.Errorf("recipient %d: unsupported recipient key type: %T", i, r)
+		}
+		keys = append(keys, k)
+	}
+
+	priv, err := GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	x509Encoded, err := marshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrMarshalPrivateKey, err)
+	}
+	defer clear(x509Encoded)
+	var fileKey [secretboxKeySize]byte
+	defer clear(fileKey[:])
+	if _, err := io.ReadFull(rand.Reader, fileKey[:]); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncryptPrivateKey, err)
+	}
+	body, err := encryptToRecipients(x509Encoded, &fileKey, keys)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrEncryptPrivateKey, err)
+	}
+	pubBytes, err := KeyToPem(priv.Public())
+	if err != nil {
+		return nil, err
+	}
+	kb := &KeysBytes{
+		PrivateBytes: pem.EncodeToMemory(&pem.Block{
+			Type:  RecipientsPrivateKeyPemType,
+			Bytes: wrapEnvelope(envelopeVersion1, body),
+		}),
+		PublicBytes: pubBytes,
+		GeneratedAt: generatedNow(),
+		Algorithm:   ECDSAP256Algorithm,
+	}
+	// Only the recipients can unwrap the file key, so the self-test loads
+	// the private key with the file key itself
+	if err := selfTestKeyPairWith(kb, func() (signature.SignerVerifier, error) {
+		return loadRecipientsPrivateKey(kb.PrivateBytes, func(stanzas []recipientStanza) ([]byte, error) {
+			if len(stanzas) != len(keys) {
+				return nil, fmt.Errorf("%d recipient stanzas, want %d", len(stanzas), len(keys))
+			}
+			return bytes.Clone(fileKey[:]), nil
+		})
+	}); err != nil {
+		return nil, err
+	}
+	return kb, nil
+}
+
+// LoadPrivateKeyForRecipient decrypts a private key generated by
+// GenerateKeyPairToRecipients with the X25519 private key of one of its
+// recipients, and returns a SignerVerifier as with LoadPrivateKey. It returns
+// an error wrapping ErrDecryptFailed if identity is not a recipient.
+func LoadPrivateKeyForRecipient(key []byte, identity *ecdh.PrivateKey) (signature.SignerVerifier, error) {
+	if identity == nil || identity.Curve() != ecdh.X25519() {
+		return nil, errors.New("identity must be an X25519 private key")
+	}
+	return loadRecipientsPrivateKey(key, func(stanzas []recipientStanza) ([]byte, error) {
+		for _, stanza := range stanzas {
+			if fileKey, ok := unwrapFileKey(stanza, identity); ok {
+				return fileKey, nil
+			}
+		}
+		return nil, errors.New("the key is not encrypted to this identity")
+	})
+}
+
+// loadRecipientsPrivateKey loads a private key encrypted to recipients, with
+// the file key returned by fileKey for the recipient stanzas of the key.
+func loadRecipientsPrivateKey(key []byte, fileKey func([]recipientStanza) ([]byte, error)) (signature.SignerVerifier, error) {
+	p, rest, err := decodePemSafely(key)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		return nil, ErrInvalidPemBlock
+	}
+	if err := checkTrailingData(rest); err != nil {
+		return nil, err
+	}
+	if p.Type != RecipientsPrivateKeyPemType {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedPemType, p.Type)
+	}
+	version, body, err := unwrapEnvelope(p.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	if version != envelopeVersion1 {
+		return nil, fmt.Errorf("unsupported envelope version %d", version)
+	}
+	x509Encoded, err := decryptRecipientsEnvelope(body, fileKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrDecryptFailed, err)
+	}
+	pk, err := parsePKCS8PrivateKey(x509Encoded)
+	if err != nil {
+		return nil, err
+	}
+	return loadSignerVerifier(pk, defaultHashForPrivateKey(pk), RSAPKCS1v15Padding)
+}
+
+// encryptToRecipients encrypts plaintext with fileKey, a random
+// secretboxKeySize key, and wraps fileKey for each recipient.
+func encryptToRecipients(plaintext []byte, fileKey *[secretboxKeySize]byte, recipients []*ecdh.PublicKey) ([]byte, error) {
+	env := recipientsEnvelope{
+		KDF: recipientsKDF{Name: recipientsKDFName},
+		Cipher: envelopeCipher{
+			Name:  nameSecretBox,
+			Nonce: make([]byte, secretboxNonceSize),
+		},
+	}
+	for _, r := range recipients {
+		stanza, err := wrapFileKey(fileKey[:], r)
+		if err != nil {
+			return nil, err
+		}
+		env.KDF.Recipients = append(env.KDF.Recipients, stanza)
+	}
+	if _, err := io.ReadFull(rand.Reader, env.Cipher.Nonce); err != nil {
+		return nil, err
+	}
+	var nonce [secretboxNonceSize]byte
+	copy(nonce[:], env.Cipher.Nonce)
+	env.Ciphertext = secretbox.Seal(nil, plaintext, &nonce, fileKey)
+	return json.Marshal(env)
+}
+
+// decryptRecipientsEnvelope decrypts the JSON body of a recipients envelope
+// with the file key returned by fileKey, which is zeroed afterwards.
+func decryptRecipientsEnvelope(data []byte, fileKey func([]recipientStanza) ([]byte, error)) ([]byte, error) {
+	var env recipientsEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return nil, err
+	}
+	if env.KDF.Name != recipientsKDFName {
+		return nil, fmt.Errorf("unsupported kdf: %q", env.KDF.Name)
+	}
+	if env.Cipher.Name != nameSecretBox {
+		return nil, fmt.Errorf("unknown cipher name %q", env.Cipher.Name)
+	}
+	if len(env.Cipher.Nonce) != secretboxNonceSize {
+		return nil, errors.New("incorrect nonce size")
+	}
+	unwrapped, err := fileKey(env.KDF.Recipients)
+	if err != nil {
+		return nil, err
+	}
+	var key [secretboxKeySize]byte
+	defer clear(key[:])
+	copy(key[:], unwrapped)
+	clear(unwrapped)
+	var nonce [secretboxNonceSize]byte
+	copy(nonce[:], env.Cipher.Nonce)
+	plaintext, ok := secretbox.Open(nil, env.Ciphertext, &nonce, &key)
+	if !ok {
+		return nil, errors.New("decryption failed")
+	}
+	return plaintext, nil
+}
+
+// wrapFileKey encrypts fileKey for recipient, with a fresh ephemeral key.
+func wrapFileKey(fileKey []byte, recipient *ecdh.PublicKey) (recipientStanza, error) {
+	ephemeral, err := ecdh.X25519().GenerateKey(rand.Reader)
+	if err != nil {
+		return recipientStanza{}, err
+	}
+	shared, err := ephemeral.ECDH(recipient)
+	if err != nil {
+		return recipientStanza{}, err
+	}
+	wrapKey, err := recipientWrapKey(shared, ephemeral.PublicKey(), recipient)
+	if err != nil {
+		return recipientStanza{}, err
+	}
+	defer clear(wrapKey[:])
+	stanza := recipientStanza{
+		Ephemeral: ephemeral.PublicKey().Bytes(),
+		Nonce:     make([]byte, secretboxNonceSize),
+	}
+	if _, err := io.ReadFull(rand.Reader, stanza.Nonce); err != nil {
+		return recipientStanza{}, err
+	}
+	var nonce [secretboxNonceSize]byte
+	copy(nonce[:], stanza.Nonce)
+	stanza.WrappedKey = secretbox.Seal(nil, fileKey, &nonce, &wrapKey)
+	return stanza, nil
+}
+
+// unwrapFileKey decrypts the file key of stanza with identity, and reports
+// whether the stanza was for identity.
+func unwrapFileKey(stanza recipientStanza, identity *ecdh.PrivateKey) ([]byte, bool) {
+	if len(stanza.Nonce) != secretboxNonceSize {
+		return nil, false
+	}
+	ephemeral, err := ecdh.X25519().NewPublicKey(stanza.Ephemeral)
+	if err != nil {
+		return nil, false
+	}
+	shared, err := identity.ECDH(ephemeral)
+	if err != nil {
+		return nil, false
+	}
+	wrapKey, err := recipientWrapKey(shared, ephemeral, identity.PublicKey())
+	if err != nil {
+		return nil, false
+	}
+	defer clear(wrapKey[:])
+	var nonce [secretboxNonceSize]byte
+	copy(nonce[:], stanza.Nonce)
+	fileKey, ok := secretbox.Open(nil, stanza.WrappedKey, &nonce, &wrapKey)
+	if !ok || len(fileKey) != secretboxKeySize {
+		return nil, false
+	}
+	return fileKey, true
+}
+
+// recipientWrapKey derives the key wrapping the file key from the shared
+// secret of an X25519 exchange, and zeroes the secret. The salt binds the key
+// to both the ephemeral and the recipient public keys.
+func recipientWrapKey(shared []byte, ephemeral, recipient *ecdh.PublicKey) ([secretboxKeySize]byte, error) {
+	defer clear(shared)
+	var wrapKey [secretboxKeySize]byte
+	salt := append(append([]byte{}, ephemeral.Bytes()...), recipient.Bytes()...)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, shared, salt, []byte(recipientsHKDFInfo)), wrapKey[:]); err != nil {
+		return wrapKey, err
+	}
+	return wrapKey, nil
+}
@@ -0,0 +1,93 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateKeyPairToRecipients(t *testing.T) {
+	var identities []*ecdh.PrivateKey
+	var recipients []crypto.PublicKey
+	for i := 0; i < 3; i++ {
+		id, err := ecdh.X25519().GenerateKey(rand.Reader)
+		require.NoError(t, err)
+		identities = append(identities, id)
+		recipients = append(recipients, id.PublicKey())
+	}
+	keys, err := GenerateKeyPairToRecipients(recipients)
+	require.NoError(t, err)
+	p, _ := pem.Decode(keys.PrivateBytes)
+	require.Equal(t, RecipientsPrivateKeyPemType, p.Type)
+
+	payload := []byte("payload")
+	verifier, err := LoadPublicKeyVerifier(keys.PublicBytes, crypto.SHA256)
+	require.NoError(t, err)
+	for _, id := range identities {
+		sv, err := LoadPrivateKeyForRecipient(keys.PrivateBytes, id)
+		require.NoError(t, err)
+		sig, err := sv.SignMessage(bytes.NewReader(payload))
+		require.NoError(t, err)
+		require.NoError(t, verifier.VerifySignature(bytes.NewReader(sig), bytes.NewReader(payload)))
+	}
+
+	other, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = LoadPrivateKeyForRecipient(keys.PrivateBytes, other)
+	require.ErrorIs(t, err, ErrDecryptFailed)
+
+	// The passphrase loaders don't accept keys encrypted to recipients
+	_, err = LoadPrivateKey(keys.PrivateBytes, nil)
+	require.ErrorIs(t, err, ErrUnsupportedPemType)
+}
+
+func TestGenerateKeyPairToRecipientsErrors(t *testing.T) {
+	_, err := GenerateKeyPairToRecipients(nil)
+	require.EqualError(t, err, "no recipients")
+
+	p256, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = GenerateKeyPairToRecipients([]crypto.PublicKey{p256.PublicKey()})
+	require.EqualError(t, err, "recipient 0: unsupported recipient key type: *ecdh.PublicKey")
+	_, err = LoadPrivateKeyForRecipient(nil, p256)
+	require.EqualError(t, err, "identity must be an X25519 private key")
+
+	id, err := ecdh.X25519().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	passKeys, err := GenerateKeyPair(pass("hello"))
+	require.NoError(t, err)
+	_, err = LoadPrivateKeyForRecipient(passKeys.PrivateBytes, id)
+	require.ErrorIs(t, err, ErrUnsupportedPemType)
+
+	// Tampering with the ciphertext is detected
+	keys, err := GenerateKeyPairToRecipients([]crypto.PublicKey{id.PublicKey()})
+	require.NoError(t, err)
+	p, _ := pem.Decode(keys.PrivateBytes)
+	version, body, err := unwrapEnvelope(p.Bytes)
+	require.NoError(t, err)
+	require.Equal(t, byte(envelopeVersion1), version)
+	tampered := bytes.Replace(body, []byte(`"ciphertext":"`), []byte(`"ciphertext":"AAAA`), 1)
+	p.Bytes = wrapEnvelope(envelopeVersion1, tampered)
+	_, err = LoadPrivateKeyForRecipient(pem.EncodeToMemory(p), id)
+	require.ErrorIs(t, err, ErrDecryptFailed)
+}
@@ -0,0 +1,136 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+
+	"github.com/sigstore/sigstore/pkg/signature"
+)
+
+// RSAPadding is the signature scheme used with an RSA key.
+type RSAPadding int
+
+const (
+	// RSAPKCS1v15Padding selects RSASSA-PKCS1-v1_5 signatures. It is the
+	// default for keys without an RSAPaddingPemHeader.
+	RSAPKCS1v15Padding RSAPadding = iota
+	// RSAPSSPadding selects RSASSA-PSS signatures, with a salt as long as
+	// the hash.
+	RSAPSSPadding
+)
+
+// RSAPaddingPemHeader is the PEM header recording the padding of the keys
+// generated by GenerateRSAKeyPairWithPadding. It is set on both the private
+// and the public key, so that the loaders of this package sign and verify
+// with the same scheme.
+const RSAPaddingPemHeader = "RSA-Padding"
+
+func (p RSAPadding) String() string {
+	switch p {
+	case RSAPKCS1v15Padding:
+		return "PKCS1v15"
+	case RSAPSSPadding:
+		return "PSS"
+	default:
+		return fmt.Sprintf("RSAPadding(%d)", int(p))
+	}
+}
+
+// rsaPaddingFromHeaders returns the padding recorded in PEM headers, or
+// RSAPKCS1v15Padding if there is none.
+func rsaPaddingFromHeaders(headers map[string]string) (RSAPadding, error) {
+	v, ok := headers[RSAPaddingPemHeader]
+	if !ok {
+		return RSAPKCS1v15Padding, nil
+	}
+	switch v {
+	case RSAPKCS1v15Padding.String():
+		return RSAPKCS1v15Padding, nil
+	case RSAPSSPadding.String():
+		return RSAPSSPadding, nil
+	default:
+		return 0, fmt.Errorf("unsupported %s header: %q", RSAPaddingPemHeader, v)
+	}
+}
+
+// privateKeyRSAPadding returns the padding recorded in the headers of a
+// cosign PEM private key.
+func privateKeyRSAPadding(key []byte) (RSAPadding, error) {
+	p, err := decodePrivateKeyPem(key)
+	if err != nil {
+		return 0, err
+	}
+	return rsaPaddingFromHeaders(p.Headers)
+}
+
+// GenerateRSAKeyPairWithPadding is GenerateRSAKeyPair, with the signature
+// scheme selected by padding. RSAPKCS1v15Padding keys are identical to the
+// ones of GenerateRSAKeyPair; RSAPSSPadding keys carry an
+// RSAPaddingPemHeader, which LoadPrivateKey and LoadPublicKeyVerifier honor.
+func GenerateRSAKeyPairWithPadding(pf PassFunc, bits int, padding RSAPadding) (*KeysBytes, error) {
+	switch padding {
+	case RSAPKCS1v15Padding:
+		return GenerateRSAKeyPair(pf, bits)
+	case RSAPSSPadding:
+	default:
+		return nil, fmt.Errorf("unsupported rsa padding: %v", padding)
+	}
+	keys, err := GenerateRSAKeyPair(pf, bits)
+	if err != nil {
+		return nil, err
+	}
+	if keys.PrivateBytes, err = setPemHeader(keys.PrivateBytes, RSAPaddingPemHeader, padding.String()); err != nil {
+		return nil, err
+	}
+	if keys.PublicBytes, err = setPemHeader(keys.PublicBytes, RSAPaddingPemHeader, padding.String()); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// setPemHeader re-encodes the single PEM block of pemBytes with a header set.
+func setPemHeader(pemBytes []byte, key, value string) ([]byte, error) {
+	p, _ := pem.Decode(pemBytes)
+	if p == nil {
+		return nil, ErrInvalidPemBlock
+	}
+	if p.Headers == nil {
+		p.Headers = map[string]string{}
+	}
+	p.Headers[key] = value
+	return pem.EncodeToMemory(p), nil
+}
+
+// rsaPSSOptions returns the PSS options used by cosign for hashFunc.
+func rsaPSSOptions(hashFunc crypto.Hash) *rsa.PSSOptions {
+	return &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash, Hash: hashFunc}
+}
+
+// loadRSASignerVerifier returns the SignerVerifier for pk using padding.
+func loadRSASignerVerifier(pk *rsa.PrivateKey, hashFunc crypto.Hash, padding RSAPadding) (signature.SignerVerifier, error) {
+	switch padding {
+	case RSAPKCS1v15Padding:
+		return signature.LoadRSAPKCS1v15SignerVerifier(pk, hashFunc)
+	case RSAPSSPadding:
+		return signature.LoadRSAPSSSignerVerifier(pk, hashFunc, rsaPSSOptions(hashFunc))
+	default:
+		return nil, fmt.Errorf("unsupported rsa padding: %v", padding)
+	}
+}
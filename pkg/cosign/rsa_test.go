@@ -0,0 +1,97 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateRSAKeyPairWithPadding(t *testing.T) {
+	payload := []byte("payload")
+	sigs := map[RSAPadding][]byte{}
+	pubs := map[RSAPadding][]byte{}
+	for _, padding := range []RSAPadding{RSAPKCS1v15Padding, RSAPSSPadding} {
+		t.Run(padding.String(), func(t *testing.T) {
+			keys, err := GenerateRSAKeyPairWithPadding(pass("hello"), 2048, padding)
+			require.NoError(t, err)
+			require.NoError(t, keys.Validate([]byte("hello")))
+			_, headers, err := LoadPublicKeyWithMetadata(keys.PublicBytes)
+			require.NoError(t, err)
+			if padding == RSAPKCS1v15Padding {
+				require.Empty(t, headers)
+			} else {
+				require.Equal(t, "PSS", headers[RSAPaddingPemHeader])
+			}
+
+			sv, err := LoadPrivateKey(keys.PrivateBytes, []byte("hello"))
+			require.NoError(t, err)
+			sig, err := sv.SignMessage(bytes.NewReader(payload))
+			require.NoError(t, err)
+			v, err := LoadPublicKeyVerifier(keys.PublicBytes, crypto.SHA256)
+			require.NoError(t, err)
+			require.NoError(t, v.VerifySignature(bytes.NewReader(sig), bytes.NewReader(payload)))
+
+			// The padding survives a password change
+			changed, err := ChangePrivateKeyPassword(keys.PrivateBytes, []byte("hello"), []byte("world"))
+			require.NoError(t, err)
+			sv, err = LoadPrivateKeyWithHash(changed, []byte("world"), crypto.SHA384)
+			require.NoError(t, err)
+			sig384, err := sv.SignMessage(bytes.NewReader(payload))
+			require.NoError(t, err)
+			v, err = LoadPublicKeyVerifier(keys.PublicBytes, crypto.SHA384)
+			require.NoError(t, err)
+			require.NoError(t, v.VerifySignature(bytes.NewReader(sig384), bytes.NewReader(payload)))
+
+			sigs[padding] = sig
+			pubs[padding] = keys.PublicBytes
+		})
+	}
+
+	// A PSS signature doesn't verify as PKCS1v15, and vice versa
+	pub := mustLoadPublicKey(t, pubs[RSAPSSPadding]).(*rsa.PublicKey)
+	require.Error(t, VerifyBytes(pub, payload, sigs[RSAPSSPadding]))
+	v, err := LoadPublicKeyVerifier(pubs[RSAPSSPadding], crypto.SHA256)
+	require.NoError(t, err)
+	require.Error(t, v.VerifySignature(bytes.NewReader(sigs[RSAPKCS1v15Padding]), bytes.NewReader(payload)))
+
+	_, err = GenerateRSAKeyPairWithPadding(pass("hello"), 2048, RSAPadding(42))
+	require.EqualError(t, err, "unsupported rsa padding: RSAPadding(42)")
+}
+
+func TestLoadRSAPrivateKeyPSS(t *testing.T) {
+	keys, err := GenerateRSAKeyPairWithPadding(pass("hello"), 2048, RSAPSSPadding)
+	require.NoError(t, err)
+	_, err = LoadRSAPrivateKey(keys.PrivateBytes, []byte("hello"), crypto.SHA256)
+	require.EqualError(t, err, "key uses PSS padding, load it with LoadPrivateKey")
+}
+
+func TestRSAPaddingHeaderInvalid(t *testing.T) {
+	keys, err := GenerateRSAKeyPair(pass("hello"), 2048)
+	require.NoError(t, err)
+	priv, err := setPemHeader(keys.PrivateBytes, RSAPaddingPemHeader, "OAEP")
+	require.NoError(t, err)
+	_, err = LoadPrivateKey(priv, []byte("hello"))
+	require.EqualError(t, err, `unsupported RSA-Padding header: "OAEP"`)
+	pub, err := setPemHeader(keys.PublicBytes, RSAPaddingPemHeader, "OAEP")
+	require.NoError(t, err)
+	_, err = LoadPublicKeyVerifier(pub, crypto.SHA256)
+	require.EqualError(t, err, `unsupported RSA-Padding header: "OAEP"`)
+}
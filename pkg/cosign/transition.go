@@ -0,0 +1,85 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+)
+
+// TransitionStatement is the endorsement of a new public key by an old one,
+// proving continuity when a key is rotated, e.g. to another algorithm.
+type TransitionStatement struct {
+	// NewPublicKey is the new public key, PEM-encoded with KeyToPem.
+	NewPublicKey []byte `json:"newPublicKey"`
+	// Digest is the hex-encoded SHA256 digest of NewPublicKey.
+	Digest string `json:"digest"`
+	// Signature is the signature of the old key over the SHA256 digest of
+	// NewPublicKey.
+	Signature []byte `json:"signature"`
+}
+
+// SignKeyTransition signs a TransitionStatement for the public key of newKeys
+// with the old ECDSA private key, decrypted with oldPass. The new public key is
+// re-encoded with KeyToPem first, so that the statement doesn't depend on the
+// headers or formatting of newKeys.PublicBytes.
+func SignKeyTransition(oldKey, oldPass []byte, newKeys *KeysBytes) (*TransitionStatement, error) {
+	if newKeys == nil {
+		return nil, errors.New("new keys are required")
+	}
+	newPub, err := LoadPublicKey(newKeys.PublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("loading new public key: %w", err)
+	}
+	payload, err := KeyToPem(newPub)
+	if err != nil {
+		return nil, err
+	}
+	sv, err := LoadECDSAPrivateKey(oldKey, oldPass)
+	if err != nil {
+		return nil, fmt.Errorf("loading old private key: %w", err)
+	}
+	if EqualPublicKeys(sv.Public(), newPub) {
+		return nil, errors.New("new key is the same as the old key")
+	}
+	sig, err := sv.SignMessage(bytes.NewReader(payload))
+	if err != nil {
+		return nil, fmt.Errorf("signing transition statement: %w", err)
+	}
+	digest := sha256.Sum256(payload)
+	return &TransitionStatement{
+		NewPublicKey: payload,
+		Digest:       hex.EncodeToString(digest[:]),
+		Signature:    sig,
+	}, nil
+}
+
+// Verify checks that the statement was signed by oldPub, and returns the new
+// public key it endorses.
+func (s *TransitionStatement) Verify(oldPub crypto.PublicKey) (crypto.PublicKey, error) {
+	digest := sha256.Sum256(s.NewPublicKey)
+	if hex.EncodeToString(digest[:]) != s.Digest {
+		return nil, errors.New("transition statement digest mismatch")
+	}
+	if err := VerifyBytes(oldPub, s.NewPublicKey, s.Signature); err != nil {
+		return nil, fmt.Errorf("verifying transition statement: %w", err)
+	}
+	return LoadPublicKey(s.NewPublicKey)
+}
@@ -0,0 +1,65 @@
+//
+// Copyright 2024 The Sigstore Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cosign
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignKeyTransition(t *testing.T) {
+	oldKeys, err := GenerateKeyPair(pass("old"))
+	require.NoError(t, err)
+	newKeys, err := GenerateKeyPairWithAlgorithm(pass("new"), ED25519Algorithm)
+	require.NoError(t, err)
+
+	statement, err := SignKeyTransition(oldKeys.PrivateBytes, []byte("old"), newKeys)
+	require.NoError(t, err)
+	require.Equal(t, newKeys.PublicBytes, statement.NewPublicKey)
+	require.Len(t, statement.Digest, 64)
+
+	oldPub := mustLoadPublicKey(t, oldKeys.PublicBytes)
+	newPub, err := statement.Verify(oldPub)
+	require.NoError(t, err)
+	require.True(t, EqualPublicKeys(mustLoadPublicKey(t, newKeys.PublicBytes), newPub))
+
+	// The statement only verifies with the old key
+	otherKeys, err := GenerateKeyPair(pass("other"))
+	require.NoError(t, err)
+	_, err = statement.Verify(mustLoadPublicKey(t, otherKeys.PublicBytes))
+	require.ErrorContains(t, err, "verifying transition statement")
+
+	// Swapping the endorsed key is detected
+	tampered := *statement
+	tampered.NewPublicKey = otherKeys.PublicBytes
+	_, err = tampered.Verify(oldPub)
+	require.EqualError(t, err, "transition statement digest mismatch")
+
+	// So is a corrupted signature
+	tampered = *statement
+	tampered.Signature = append([]byte{}, statement.Signature...)
+	tampered.Signature[len(tampered.Signature)-1] ^= 1
+	_, err = tampered.Verify(oldPub)
+	require.ErrorContains(t, err, "verifying transition statement")
+
+	_, err = SignKeyTransition(oldKeys.PrivateBytes, []byte("wrong"), newKeys)
+	require.ErrorIs(t, err, ErrDecryptFailed)
+	_, err = SignKeyTransition(oldKeys.PrivateBytes, []byte("old"), oldKeys)
+	require.EqualError(t, err, "new key is the same as the old key")
+	_, err = SignKeyTransition(oldKeys.PrivateBytes, []byte("old"), nil)
+	require.EqualError(t, err, "new keys are required")
+}
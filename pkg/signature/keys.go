@@ -17,6 +17,7 @@ package signature
 import (
 	"context"
 	"crypto"
+	"encoding/pem"
 	"errors"
 	"fmt"
 	"strings"
@@ -33,17 +34,19 @@ import (
 	"github.com/sigstore/sigstore/pkg/signature/kms"
 )
 
-// LoadPublicKey is a wrapper for VerifierForKeyRef, hardcoding SHA256 as the hash algorithm
+// LoadPublicKey is a wrapper for VerifierForKeyRef, using the hash algorithm
+// matching the key
 func LoadPublicKey(ctx context.Context, keyRef string) (verifier signature.Verifier, err error) {
-	return VerifierForKeyRef(ctx, keyRef, crypto.SHA256)
+	return VerifierForKeyRef(ctx, keyRef, crypto.Hash(0))
 }
 
 // VerifierForKeyRef parses the given keyRef, loads the key and returns an appropriate
-// verifier using the provided hash algorithm
+// verifier using the provided hash algorithm. A zero hash algorithm selects the one
+// cosign.LoadPrivateKey signs with, see cosign.DefaultHashForKey, and SHA256 for KMS keys.
 func VerifierForKeyRef(ctx context.Context, keyRef string, hashAlgorithm crypto.Hash) (verifier signature.Verifier, err error) {
 	// The key could be plaintext, in a file, at a URL, or in KMS.
 	var perr *kms.ProviderNotFoundError
-	kmsKey, err := kms.Get(ctx, keyRef, hashAlgorithm)
+	kmsKey, err := kms.Get(ctx, keyRef, kmsHashAlgorithm(hashAlgorithm))
 	switch {
 	case err == nil:
 		// KMS specified
@@ -69,7 +72,32 @@ func VerifierForKeyRef(ctx context.Context, keyRef string, hashAlgorithm crypto.
 		return nil, fmt.Errorf("pem to public key: %w", err)
 	}
 
-	return signature.LoadVerifier(pubKey, hashAlgorithm)
+	return loadVerifier(raw, pubKey, hashAlgorithm)
+}
+
+// kmsHashAlgorithm returns the hash algorithm to get KMS keys with, which
+// defaults to SHA256 like in SignerVerifierFromKeyRef.
+func kmsHashAlgorithm(hashAlgorithm crypto.Hash) crypto.Hash {
+	if hashAlgorithm == crypto.Hash(0) {
+		return crypto.SHA256
+	}
+	return hashAlgorithm
+}
+
+// loadVerifier returns a verifier for pub, parsed from the PEM block raw, using
+// the hash algorithm matching pub if hashAlgorithm is zero. Keys with an
+// RSA-Padding header are loaded with cosign.LoadPublicKeyVerifier, so that PSS
+// keys are verified with the scheme cosign.LoadPrivateKey signs with.
+func loadVerifier(raw []byte, pub crypto.PublicKey, hashAlgorithm crypto.Hash) (signature.Verifier, error) {
+	if hashAlgorithm == crypto.Hash(0) {
+		hashAlgorithm = cosign.DefaultHashForKey(pub)
+	}
+	if p, _ := pem.Decode(raw); p != nil {
+		if _, ok := p.Headers[cosign.RSAPaddingPemHeader]; ok {
+			return cosign.LoadPublicKeyVerifier(raw, hashAlgorithm)
+		}
+	}
+	return signature.LoadVerifier(pub, hashAlgorithm)
 }
 
 func loadKey(keyPath string, pf cosign.PassFunc) (signature.SignerVerifier, error) {
@@ -87,13 +115,14 @@ func loadKey(keyPath string, pf cosign.PassFunc) (signature.SignerVerifier, erro
 	return cosign.LoadPrivateKey(kb, pass)
 }
 
-// LoadPublicKeyRaw loads a verifier from a PEM-encoded public key
+// LoadPublicKeyRaw loads a verifier from a PEM-encoded public key. A zero hash
+// algorithm selects the one matching the key, see cosign.DefaultHashForKey.
 func LoadPublicKeyRaw(raw []byte, hashAlgorithm crypto.Hash) (signature.Verifier, error) {
 	pub, err := cryptoutils.UnmarshalPEMToPublicKey(raw)
 	if err != nil {
 		return nil, err
 	}
-	return signature.LoadVerifier(pub, hashAlgorithm)
+	return loadVerifier(raw, pub, hashAlgorithm)
 }
 
 func SignerFromKeyRef(ctx context.Context, keyRef string, pf cosign.PassFunc) (signature.Signer, error) {
@@ -168,10 +197,15 @@ func SignerVerifierFromKeyRef(ctx context.Context, keyRef string, pf cosign.Pass
 	return loadKey(keyRef, pf)
 }
 
+// PublicKeyFromKeyRef is PublicKeyFromKeyRefWithHashAlgo, using the hash algorithm
+// matching the key.
 func PublicKeyFromKeyRef(ctx context.Context, keyRef string) (signature.Verifier, error) {
-	return PublicKeyFromKeyRefWithHashAlgo(ctx, keyRef, crypto.SHA256)
+	return PublicKeyFromKeyRefWithHashAlgo(ctx, keyRef, crypto.Hash(0))
 }
 
+// PublicKeyFromKeyRefWithHashAlgo loads the public key referenced by keyRef and
+// returns a verifier using hashAlgorithm, or the hash algorithm matching the key
+// if it is zero.
 func PublicKeyFromKeyRefWithHashAlgo(ctx context.Context, keyRef string, hashAlgorithm crypto.Hash) (signature.Verifier, error) {
 	if strings.HasPrefix(keyRef, kubernetes.KeyReference) {
 		s, err := kubernetes.GetKeyPairSecret(ctx, keyRef)
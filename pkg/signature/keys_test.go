@@ -15,10 +15,12 @@
 package signature
 
 import (
+	"bytes"
 	"context"
 	"crypto"
 	"errors"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/sigstore/cosign/v2/pkg/blob"
@@ -111,6 +113,58 @@ func TestPublicKeyFromFileRef(t *testing.T) {
 	}
 }
 
+func TestPublicKeyFromKeyRefMatchesSigner(t *testing.T) {
+	ctx := context.Background()
+	for name, generate := range map[string]func() (*cosign.KeysBytes, error){
+		"ecdsa-p256": func() (*cosign.KeysBytes, error) {
+			return cosign.GenerateKeyPairWithAlgorithm(pass("whatever"), cosign.ECDSAP256Algorithm)
+		},
+		"ecdsa-p384": func() (*cosign.KeysBytes, error) {
+			return cosign.GenerateKeyPairWithAlgorithm(pass("whatever"), cosign.ECDSAP384Algorithm)
+		},
+		"ecdsa-p521": func() (*cosign.KeysBytes, error) {
+			return cosign.GenerateKeyPairWithAlgorithm(pass("whatever"), cosign.ECDSAP521Algorithm)
+		},
+		"rsa-pkcs1v15": func() (*cosign.KeysBytes, error) {
+			return cosign.GenerateRSAKeyPairWithPadding(pass("whatever"), 2048, cosign.RSAPKCS1v15Padding)
+		},
+		"rsa-pss": func() (*cosign.KeysBytes, error) {
+			return cosign.GenerateRSAKeyPairWithPadding(pass("whatever"), 2048, cosign.RSAPSSPadding)
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			keys, err := generate()
+			if err != nil {
+				t.Fatalf("failed to generate keypair: %v", err)
+			}
+			privFile := filepath.Join(t.TempDir(), "cosign.key")
+			pubFile := filepath.Join(t.TempDir(), "cosign.pub")
+			if err := os.WriteFile(privFile, keys.PrivateBytes, 0o600); err != nil {
+				t.Fatalf("failed to write key file: %v", err)
+			}
+			if err := os.WriteFile(pubFile, keys.PublicBytes, 0o600); err != nil {
+				t.Fatalf("failed to write pub file: %v", err)
+			}
+
+			signer, err := SignerFromKeyRef(ctx, privFile, pass("whatever"))
+			if err != nil {
+				t.Fatalf("SignerFromKeyRef returned error: %v", err)
+			}
+			sig, err := signer.SignMessage(bytes.NewReader([]byte("payload")))
+			if err != nil {
+				t.Fatalf("SignMessage returned error: %v", err)
+			}
+			verifier, err := PublicKeyFromKeyRef(ctx, pubFile)
+			if err != nil {
+				t.Fatalf("PublicKeyFromKeyRef returned error: %v", err)
+			}
+			if err := verifier.VerifySignature(bytes.NewReader(sig), bytes.NewReader([]byte("payload"))); err != nil {
+				t.Fatalf("VerifySignature returned error: %v", err)
+			}
+		})
+	}
+}
+
 func TestPublicKeyFromEnvVar(t *testing.T) {
 	keys, err := cosign.GenerateKeyPair(pass("whatever"))
 	if err != nil {